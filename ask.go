@@ -0,0 +1,187 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrAttempts = errors.New("输入无效次数过多")
+
+/**
+ * @description: 提问选项
+ */
+type AskOptions struct {
+	Timeout      int      // 每次等待超时，单位：毫秒，默认60000
+	Attempts     int      // 最多尝试次数，默认3
+	RetryMessage string   // 输入无效时回复的提示，默认"输入无效，请重新输入"
+	CancelWords  []string // 取消关键词，默认使用CancelWords
+}
+
+func askOptions(opts []AskOptions) AskOptions {
+	opt := AskOptions{}
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if opt.Timeout <= 0 {
+		opt.Timeout = 60000
+	}
+	if opt.Attempts <= 0 {
+		opt.Attempts = 3
+	}
+	if opt.RetryMessage == "" {
+		opt.RetryMessage = "输入无效，请重新输入"
+	}
+	return opt
+}
+
+/**
+ * @description: 发送提示语并等待通过校验的输入，超时返回ErrListenTimeout，取消返回ErrCanceled，超过尝试次数返回ErrAttempts
+ * @param {string} prompt 提示语，为空时不发送
+ * @param {func(string) bool} valid 校验输入
+ * @param {AskOptions} opts 可选，提问选项
+ * @return {string} 用户输入
+ */
+func (s *Sender) Ask(prompt string, valid func(input string) bool, opts ...AskOptions) (string, error) {
+	opt := askOptions(opts)
+	if prompt != "" {
+		if _, err := s.Reply(prompt); err != nil {
+			return "", err
+		}
+	}
+	for i := 0; i < opt.Attempts; i++ {
+		if i > 0 {
+			if _, err := s.Reply(opt.RetryMessage); err != nil {
+				return "", err
+			}
+		}
+		result, err := s.ListenMessage(context.Background(), ListenOptions{Timeout: time.Duration(opt.Timeout) * time.Millisecond})
+		if err != nil {
+			return "", err
+		}
+		input := result.Text
+		if IsCancel(input, opt.CancelWords...) {
+			return "", ErrCanceled
+		}
+		if valid == nil || valid(input) {
+			return input, nil
+		}
+	}
+	return "", ErrAttempts
+}
+
+/**
+ * @description: 询问整数
+ * @param {string} prompt 提示语
+ * @param {int} min 最小值
+ * @param {int} max 最大值
+ * @return {int}
+ */
+func (s *Sender) AskInt(prompt string, min, max int, opts ...AskOptions) (int, error) {
+	var n int
+	_, err := s.Ask(prompt, func(input string) bool {
+		v, err := strconv.Atoi(strings.TrimSpace(input))
+		if err != nil || v < min || v > max {
+			return false
+		}
+		n = v
+		return true
+	}, opts...)
+	return n, err
+}
+
+/**
+ * @description: 询问选项，提示语后附带序号列表，用户可回复序号或选项内容
+ * @param {string} prompt 提示语
+ * @param {[]string} options 选项
+ * @return {int} 选项下标
+ * @return {string} 选项内容
+ */
+func (s *Sender) AskChoice(prompt string, options []string, opts ...AskOptions) (int, string, error) {
+	var sb strings.Builder
+	sb.WriteString(prompt)
+	for i, option := range options {
+		sb.WriteString(fmt.Sprintf("\n%d. %s", i+1, option))
+	}
+	index := -1
+	_, err := s.Ask(sb.String(), func(input string) bool {
+		input = strings.TrimSpace(input)
+		if v, err := strconv.Atoi(input); err == nil && v >= 1 && v <= len(options) {
+			index = v - 1
+			return true
+		}
+		for i, option := range options {
+			if strings.EqualFold(input, option) {
+				index = i
+				return true
+			}
+		}
+		return false
+	}, opts...)
+	if err != nil {
+		return -1, "", err
+	}
+	return index, options[index], nil
+}
+
+/**
+ * @description: 询问是否确认，接受是/否、y/n、yes/no、确认/确定等回复
+ * @param {string} prompt 提示语
+ * @return {bool}
+ */
+func (s *Sender) AskConfirm(prompt string, opts ...AskOptions) (bool, error) {
+	var ok bool
+	_, err := s.Ask(prompt, func(input string) bool {
+		switch strings.ToLower(strings.TrimSpace(input)) {
+		case "是", "y", "yes", "确认", "确定", "好", "ok":
+			ok = true
+			return true
+		case "否", "n", "no", "不":
+			ok = false
+			return true
+		}
+		return false
+	}, opts...)
+	return ok, err
+}
+
+/**
+ * @description: 询问符合正则表达式的内容
+ * @param {string} prompt 提示语
+ * @param {*regexp.Regexp} re 正则表达式
+ * @return {[]string} 匹配结果，下标0为完整匹配，其后为各分组
+ */
+func (s *Sender) AskRegex(prompt string, re *regexp.Regexp, opts ...AskOptions) ([]string, error) {
+	var match []string
+	_, err := s.Ask(prompt, func(input string) bool {
+		match = re.FindStringSubmatch(strings.TrimSpace(input))
+		return match != nil
+	}, opts...)
+	return match, err
+}
+
+/**
+ * @description: 询问图片，用户须发送一张图片
+ * @param {string} prompt 提示语
+ * @return {string} 图片地址
+ */
+func (s *Sender) AskImage(prompt string, opts ...AskOptions) (string, error) {
+	var image string
+	_, err := s.Ask(prompt, func(input string) bool {
+		for _, seg := range ParseMessage(input).Segments {
+			if seg.Type != "image" {
+				continue
+			}
+			if image = seg.Data["url"]; image == "" {
+				image = seg.Data["file"]
+			}
+			return image != ""
+		}
+		return false
+	}, opts...)
+	return image, err
+}
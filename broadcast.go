@@ -0,0 +1,200 @@
+package middleware
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// 保存广播进度的数据桶
+const broadcastBucket = "broadcast"
+
+/**
+ * @description: 各平台两次推送之间的最小间隔，未配置的平台使用DefaultBroadcastInterval
+ */
+var BroadcastIntervals = map[string]time.Duration{
+	"qq":   time.Second,
+	"qb":   time.Second,
+	"wx":   2 * time.Second,
+	"wb":   200 * time.Millisecond,
+	"tg":   50 * time.Millisecond,
+	"tb":   50 * time.Millisecond,
+	"wxmp": 500 * time.Millisecond,
+	"wxsv": 500 * time.Millisecond,
+}
+
+/**
+ * @description: 未配置平台的推送间隔
+ */
+var DefaultBroadcastInterval = time.Second
+
+/**
+ * @description: 推送目标
+ */
+type Target struct {
+	ImType    string `json:"imType"`
+	GroupCode string `json:"groupCode"`
+	UserID    string `json:"userID"`
+}
+
+/**
+ * @description: 目标的唯一标识，用于记录广播进度
+ * @return {string}
+ */
+func (t Target) Key() string {
+	return t.ImType + ":" + t.GroupCode + ":" + t.UserID
+}
+
+/**
+ * @description: 广播内容
+ */
+type BroadcastMessage struct {
+	Title   string
+	Content string
+}
+
+/**
+ * @description: 广播选项
+ */
+type BroadcastOptions struct {
+	Concurrency int    // 并发数，默认4
+	Retries     int    // 失败后的重试次数，默认2，为负数时不重试，重试间隔按指数增长
+	ProgressKey string // 进度记录键，不为空时在数据桶中记录已完成的目标，中断后以相同的键再次广播会跳过这些目标
+}
+
+/**
+ * @description: 单个目标的广播结果
+ */
+type BroadcastResult struct {
+	Target  Target
+	Err     error
+	Skipped bool // 根据进度记录跳过，之前已推送成功
+}
+
+/**
+ * @description: 向多个目标推送消息，按平台限速、并发发送并重试失败的推送
+ * 全部成功后清除进度记录，存在失败时保留，以便再次广播时只推送失败和未完成的目标
+ * @param {[]Target} targets 推送目标
+ * @param {BroadcastMessage} msg 广播内容
+ * @param {BroadcastOptions} opts 可选，广播选项
+ * @return {[]BroadcastResult} 与targets顺序一致的结果
+ */
+func Broadcast(targets []Target, msg BroadcastMessage, opts ...BroadcastOptions) []BroadcastResult {
+	opt := BroadcastOptions{}
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if opt.Concurrency <= 0 {
+		opt.Concurrency = 4
+	}
+	if opt.Retries < 0 {
+		opt.Retries = 0
+	} else if opt.Retries == 0 {
+		opt.Retries = 2
+	}
+	progress := loadBroadcastProgress(opt.ProgressKey)
+	pacers := map[string]*pacer{}
+	for _, t := range targets {
+		if pacers[t.ImType] == nil {
+			interval, ok := BroadcastIntervals[t.ImType]
+			if !ok {
+				interval = DefaultBroadcastInterval
+			}
+			pacers[t.ImType] = &pacer{interval: interval}
+		}
+	}
+
+	results := make([]BroadcastResult, len(targets))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, opt.Concurrency)
+	for i, t := range targets {
+		results[i].Target = t
+		mu.Lock()
+		done := progress[t.Key()]
+		mu.Unlock()
+		if done {
+			results[i].Skipped = true
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, t Target) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			var err error
+			for attempt := 0; attempt <= opt.Retries; attempt++ {
+				if attempt > 0 {
+					time.Sleep(time.Second << uint(attempt-1))
+				}
+				pacers[t.ImType].wait()
+				if err = Push(t.ImType, t.GroupCode, t.UserID, msg.Title, msg.Content); err == nil {
+					break
+				}
+			}
+			results[i].Err = err
+			if err == nil && opt.ProgressKey != "" {
+				mu.Lock()
+				progress[t.Key()] = true
+				saveBroadcastProgress(opt.ProgressKey, progress)
+				mu.Unlock()
+			}
+		}(i, t)
+	}
+	wg.Wait()
+
+	if opt.ProgressKey != "" {
+		for _, r := range results {
+			if r.Err != nil {
+				return results
+			}
+		}
+		BucketDelete(broadcastBucket, opt.ProgressKey)
+	}
+	return results
+}
+
+// 按固定间隔放行同一平台的推送
+type pacer struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func (p *pacer) wait() {
+	p.mu.Lock()
+	now := time.Now()
+	at := p.next
+	if at.Before(now) {
+		at = now
+	}
+	p.next = at.Add(p.interval)
+	p.mu.Unlock()
+	time.Sleep(time.Until(at))
+}
+
+func loadBroadcastProgress(key string) map[string]bool {
+	progress := map[string]bool{}
+	if key == "" {
+		return progress
+	}
+	var done []string
+	if data := BucketGet(broadcastBucket, key); data != "" {
+		json.Unmarshal([]byte(data), &done)
+	}
+	for _, k := range done {
+		progress[k] = true
+	}
+	return progress
+}
+
+func saveBroadcastProgress(key string, progress map[string]bool) {
+	done := make([]string, 0, len(progress))
+	for k := range progress {
+		done = append(done, k)
+	}
+	data, _ := json.Marshal(done)
+	BucketSet(broadcastBucket, key, string(data))
+}
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/beego/beego/v2/client/httplib"
+	"github.com/buger/jsonparser"
+)
+
+/**
+ * @description: 消息按钮，Data为点击后回调的数据，URL不为空时为链接按钮
+ */
+type Button struct {
+	Text string `json:"text"`
+	Data string `json:"data,omitempty"`
+	URL  string `json:"url,omitempty"`
+}
+
+/**
+ * @description: 回复带按钮的消息，不支持按钮的平台降级为带序号的文本菜单
+ * 按钮点击通过AddEventListener以CallbackEvent送达；降级时可用MatchButton匹配用户输入
+ * @param {string} text 文本内容
+ * @param {[][]Button} buttons 按钮，每个切片为一行
+ * @return {[]string} 消息ID
+ */
+func (s *Sender) ReplyWithButtons(text string, buttons [][]Button) ([]string, error) {
+	if !PlatformOf(s.GetImtype()).Buttons {
+		return s.Reply(ButtonsText(text, buttons))
+	}
+	params := map[string]interface{}{
+		"senderid": s.SenderID,
+		"text":     text,
+		"buttons":  buttons,
+	}
+	body, _ := json.Marshal(params)
+	var msgIds []string
+	if resp, err := httplib.Post(sockUrl()+"/sendButtons").Header("Content-Type", "application/json").Body(body).SetTransport(transport).Bytes(); err == nil {
+		if data, err := jsonparser.GetUnsafeString(resp, "data"); err == nil {
+			json.Unmarshal([]byte(data), &msgIds)
+			return msgIds, nil
+		}
+	}
+	return nil, errors.New("回复失败")
+}
+
+/**
+ * @description: 将按钮渲染为带序号的文本菜单，链接按钮直接附上链接
+ * @param {string} text 文本内容
+ * @param {[][]Button} buttons 按钮
+ * @return {string}
+ */
+func ButtonsText(text string, buttons [][]Button) string {
+	var sb strings.Builder
+	sb.WriteString(text)
+	i := 0
+	for _, row := range buttons {
+		for _, b := range row {
+			if b.URL != "" {
+				sb.WriteString(fmt.Sprintf("\n%s: %s", b.Text, b.URL))
+				continue
+			}
+			i++
+			sb.WriteString(fmt.Sprintf("\n%d. %s", i, b.Text))
+		}
+	}
+	return sb.String()
+}
+
+/**
+ * @description: 将用户输入的序号或按钮文字匹配为按钮，用于文本菜单降级后的Listen结果
+ * @param {[][]Button} buttons 按钮
+ * @param {string} input 用户输入
+ * @return {Button} 匹配到的按钮
+ * @return {bool} 是否匹配
+ */
+func MatchButton(buttons [][]Button, input string) (Button, bool) {
+	input = strings.TrimSpace(input)
+	index, err := strconv.Atoi(input)
+	i := 0
+	for _, row := range buttons {
+		for _, b := range row {
+			if b.URL != "" {
+				continue
+			}
+			i++
+			if (err == nil && index == i) || input == b.Text || (b.Data != "" && input == b.Data) {
+				return b, true
+			}
+		}
+	}
+	return Button{}, false
+}
@@ -0,0 +1,405 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 命令参数类型
+const (
+	ArgString   = "string"   // 单个词
+	ArgInt      = "int"      // 整数
+	ArgDuration = "duration" // 时长，如30s、5m、1h、2d、10分钟
+	ArgUser     = "user"     // 用户，支持[CQ:at,qq=123]、@123和123
+	ArgChoice   = "choice"   // Choices中的一项
+	ArgRest     = "rest"     // 剩余的全部内容，只能作为最后一个参数
+)
+
+/**
+ * @description: 命令参数定义
+ */
+type Arg struct {
+	Name     string
+	Type     string   // 参数类型，默认ArgString
+	Choices  []string // ArgChoice的可选值
+	Optional bool     // 是否可省略
+	Default  string   // 省略时的默认值，为空时不设置该参数
+}
+
+/**
+ * @description: 解析后的命令参数
+ */
+type Args map[string]interface{}
+
+func (a Args) String(name string) string {
+	v, _ := a[name].(string)
+	return v
+}
+
+func (a Args) Int(name string) int {
+	v, _ := a[name].(int)
+	return v
+}
+
+func (a Args) Duration(name string) time.Duration {
+	v, _ := a[name].(time.Duration)
+	return v
+}
+
+/**
+ * @description: 是否提供了参数（含默认值）
+ * @param {string} name 参数名称
+ * @return {bool}
+ */
+func (a Args) Has(name string) bool {
+	_, ok := a[name]
+	return ok
+}
+
+/**
+ * @description: 命令处理函数
+ */
+type HandlerFunc func(s *Sender, args Args) error
+
+/**
+ * @description: 命令中间件，包装处理函数以实现权限检查、限流等，不调用next即可拦截命令
+ */
+type Middleware func(next HandlerFunc) HandlerFunc
+
+/**
+ * @description: 命令定义
+ */
+type Command struct {
+	Name    string
+	Aliases []string
+	Help    string
+	Args    []Arg
+	Handler HandlerFunc
+	Guards  []Middleware // 仅作用于该命令的中间件，在Router.Use注册的中间件之后执行
+}
+
+/**
+ * @description: 命令参数错误，Dispatch会将错误与用法回复给用户
+ */
+type UsageError struct {
+	Command *Command
+	Msg     string
+}
+
+func (e *UsageError) Error() string {
+	return e.Msg
+}
+
+/**
+ * @description: 命令路由，解析GetMessage的内容并分发给对应的处理函数
+ */
+type Router struct {
+	Prefix      string // 命令前缀，如"/"，可为空
+	commands    []*Command
+	middlewares []Middleware
+}
+
+/**
+ * @description: 创建命令路由，并注册help命令
+ * @param {string} prefix 命令前缀
+ * @return {*Router}
+ */
+func NewRouter(prefix string) *Router {
+	r := &Router{Prefix: prefix}
+	r.Register(&Command{
+		Name:    "help",
+		Aliases: []string{"帮助"},
+		Help:    "查看命令帮助",
+		Args:    []Arg{{Name: "command", Optional: true}},
+		Handler: func(s *Sender, args Args) error {
+			if name := args.String("command"); name != "" {
+				if cmd := r.Find(name); cmd != nil {
+					_, err := s.Reply(r.Usage(cmd) + "\n" + cmd.Help)
+					return err
+				}
+			}
+			_, err := s.Reply(r.Help())
+			return err
+		},
+	})
+	return r
+}
+
+/**
+ * @description: 注册命令，同名命令会覆盖之前的定义
+ * @param {*Command} cmd 命令
+ * @return {*Router}
+ */
+func (r *Router) Register(cmd *Command) *Router {
+	for i, c := range r.commands {
+		if c.Name == cmd.Name {
+			r.commands[i] = cmd
+			return r
+		}
+	}
+	r.commands = append(r.commands, cmd)
+	return r
+}
+
+/**
+ * @description: 注册作用于全部命令的中间件，按注册顺序执行
+ * @param {...Middleware} mw 中间件
+ * @return {*Router}
+ */
+func (r *Router) Use(mw ...Middleware) *Router {
+	r.middlewares = append(r.middlewares, mw...)
+	return r
+}
+
+/**
+ * @description: 按名称或别名查找命令
+ * @param {string} name 命令名称
+ * @return {*Command}
+ */
+func (r *Router) Find(name string) *Command {
+	name = strings.TrimPrefix(name, r.Prefix)
+	for _, c := range r.commands {
+		if c.Name == name || containsString(c.Aliases, name) {
+			return c
+		}
+	}
+	return nil
+}
+
+/**
+ * @description: 生成命令用法，如"/ban <user> [duration]"
+ * @param {*Command} cmd 命令
+ * @return {string}
+ */
+func (r *Router) Usage(cmd *Command) string {
+	var sb strings.Builder
+	sb.WriteString(r.Prefix + cmd.Name)
+	for _, arg := range cmd.Args {
+		name := arg.Name
+		if arg.Type == ArgChoice {
+			name = strings.Join(arg.Choices, "|")
+		} else if arg.Type == ArgRest {
+			name += "..."
+		}
+		if arg.Optional {
+			sb.WriteString(" [" + name + "]")
+		} else {
+			sb.WriteString(" <" + name + ">")
+		}
+	}
+	return sb.String()
+}
+
+/**
+ * @description: 生成全部命令的帮助
+ * @return {string}
+ */
+func (r *Router) Help() string {
+	lines := make([]string, 0, len(r.commands))
+	for _, c := range r.commands {
+		line := r.Usage(c)
+		if c.Help != "" {
+			line += "  " + c.Help
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+/**
+ * @description: 解析文本为命令与参数
+ * @param {string} text 消息内容
+ * @return {*Command} 未匹配任何命令时为nil
+ * @return {Args} 参数
+ * @return {error} 参数错误时为*UsageError
+ */
+func (r *Router) Parse(text string) (*Command, Args, error) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, r.Prefix) {
+		return nil, nil, nil
+	}
+	tokens := splitArgs(strings.TrimPrefix(text, r.Prefix))
+	if len(tokens) == 0 {
+		return nil, nil, nil
+	}
+	cmd := r.Find(tokens[0].text)
+	if cmd == nil {
+		return nil, nil, nil
+	}
+	args := Args{}
+	tokens = tokens[1:]
+	for i, arg := range cmd.Args {
+		if arg.Type == ArgRest {
+			if i < len(tokens) {
+				args[arg.Name] = strings.TrimSpace(text[len(text)-tokens[i].rest:])
+			} else if arg.Default != "" {
+				args[arg.Name] = arg.Default
+			} else if !arg.Optional {
+				return cmd, nil, &UsageError{Command: cmd, Msg: "缺少参数" + arg.Name}
+			}
+			return cmd, args, nil
+		}
+		raw := arg.Default
+		if i < len(tokens) {
+			raw = tokens[i].text
+		} else if !arg.Optional {
+			return cmd, nil, &UsageError{Command: cmd, Msg: "缺少参数" + arg.Name}
+		} else if raw == "" {
+			continue
+		}
+		v, err := parseArg(arg, raw)
+		if err != nil {
+			return cmd, nil, &UsageError{Command: cmd, Msg: fmt.Sprintf("参数%s无效: %s", arg.Name, err)}
+		}
+		args[arg.Name] = v
+	}
+	if len(tokens) > len(cmd.Args) {
+		return cmd, nil, &UsageError{Command: cmd, Msg: "参数过多"}
+	}
+	return cmd, args, nil
+}
+
+/**
+ * @description: 解析当前消息并执行对应命令，参数错误时回复错误与用法
+ * @param {*Sender} s 当前用户
+ * @return {bool} 是否匹配到命令
+ */
+func (r *Router) Dispatch(s *Sender) (bool, error) {
+	cmd, args, err := r.Parse(s.GetMessage())
+	if cmd == nil {
+		return false, nil
+	}
+	var usage *UsageError
+	if errors.As(err, &usage) {
+		s.Reply(usage.Msg + "\n用法: " + r.Usage(cmd))
+		return true, err
+	}
+	handler := cmd.Handler
+	for i := len(cmd.Guards) - 1; i >= 0; i-- {
+		handler = cmd.Guards[i](handler)
+	}
+	for i := len(r.middlewares) - 1; i >= 0; i-- {
+		handler = r.middlewares[i](handler)
+	}
+	return true, handler(s, args)
+}
+
+func parseArg(arg Arg, raw string) (interface{}, error) {
+	switch arg.Type {
+	case ArgInt:
+		return strconv.Atoi(raw)
+	case ArgDuration:
+		return ParseDuration(raw)
+	case ArgUser:
+		return ParseUser(raw)
+	case ArgChoice:
+		for _, c := range arg.Choices {
+			if strings.EqualFold(c, raw) {
+				return c, nil
+			}
+		}
+		return nil, fmt.Errorf("可选值为%s", strings.Join(arg.Choices, "、"))
+	}
+	return raw, nil
+}
+
+var durationPattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*(ms|s|m|h|d|w|毫秒|秒|分钟|分|小时|时|天|周)?$`)
+
+var durationUnits = map[string]time.Duration{
+	"":   time.Second,
+	"ms": time.Millisecond, "毫秒": time.Millisecond,
+	"s": time.Second, "秒": time.Second,
+	"m": time.Minute, "分": time.Minute, "分钟": time.Minute,
+	"h": time.Hour, "时": time.Hour, "小时": time.Hour,
+	"d": 24 * time.Hour, "天": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour, "周": 7 * 24 * time.Hour,
+}
+
+/**
+ * @description: 解析时长，支持30s、5m、1h30m、2d、10分钟等写法，无单位时按秒，不接受负数
+ * @param {string} s 时长
+ * @return {time.Duration}
+ */
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if m := durationPattern.FindStringSubmatch(s); m != nil {
+		n, _ := strconv.ParseFloat(m[1], 64)
+		return time.Duration(n * float64(durationUnits[m[2]])), nil
+	}
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return d, nil
+	}
+	return 0, fmt.Errorf("无法识别的时长%s", s)
+}
+
+var atPattern = regexp.MustCompile(`^\[CQ:at,qq=([^\],]+)[^\]]*\]$`)
+
+/**
+ * @description: 不带CQ码的用户ID格式，默认支持纯数字、微信wxid_开头的ID、QQ官方机器人的32位openid和tg的@username，可按平台修改
+ */
+var UserIDPattern = regexp.MustCompile(`^(?:\d+|wxid_[\w-]+|[0-9A-Fa-f]{32}|@[A-Za-z]\w{4,31})$`)
+
+/**
+ * @description: 解析用户，支持[CQ:at,qq=123]、@123和123，返回用户ID，后两种写法须符合UserIDPattern。
+ * tg的@username原样返回，其余写法去掉开头的@
+ * @param {string} s 用户
+ * @return {string}
+ */
+func ParseUser(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if m := atPattern.FindStringSubmatch(s); m != nil {
+		return UnescapeCQ(m[1]), nil
+	}
+	if id := strings.TrimPrefix(s, "@"); UserIDPattern.MatchString(id) {
+		return id, nil
+	}
+	if UserIDPattern.MatchString(s) {
+		return s, nil
+	}
+	return "", fmt.Errorf("无法识别的用户%s", s)
+}
+
+type argToken struct {
+	text string
+	rest int // 从该词开始到文本末尾的字节数
+}
+
+// 按空白拆分参数，支持双引号包裹含空格的参数，CQ码作为整体
+func splitArgs(text string) []argToken {
+	var tokens []argToken
+	for i := 0; i < len(text); {
+		if r, size := utf8.DecodeRuneInString(text[i:]); unicode.IsSpace(r) {
+			i += size
+			continue
+		}
+		start := i
+		switch {
+		case text[i] == '"':
+			end := strings.IndexByte(text[i+1:], '"')
+			if end >= 0 {
+				tokens = append(tokens, argToken{text: text[i+1 : i+1+end], rest: len(text) - start})
+				i += end + 2
+				continue
+			}
+		case strings.HasPrefix(text[i:], "[CQ:"):
+			if end := strings.IndexByte(text[i:], ']'); end >= 0 {
+				tokens = append(tokens, argToken{text: text[i : i+end+1], rest: len(text) - start})
+				i += end + 1
+				continue
+			}
+		}
+		end := strings.IndexFunc(text[i:], unicode.IsSpace)
+		if end < 0 {
+			end = len(text) - i
+		}
+		tokens = append(tokens, argToken{text: text[i : i+end], rest: len(text) - start})
+		i += end
+	}
+	return tokens
+}
@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRouterParse(t *testing.T) {
+	r := NewRouter("/")
+	r.Register(&Command{Name: "ban", Args: []Arg{
+		{Name: "user", Type: ArgUser},
+		{Name: "duration", Type: ArgDuration, Optional: true, Default: "10m"},
+	}})
+	r.Register(&Command{Name: "say", Aliases: []string{"说"}, Args: []Arg{{Name: "text", Type: ArgRest}}})
+	r.Register(&Command{Name: "mode", Args: []Arg{{Name: "mode", Type: ArgChoice, Choices: []string{"on", "off"}}}})
+	tests := []struct {
+		text  string
+		cmd   string // 为空表示未匹配命令
+		args  Args
+		usage bool // 是否返回*UsageError
+	}{
+		{"/ban @123 5m", "ban", Args{"user": "123", "duration": 5 * time.Minute}, false},
+		{"/ban [CQ:at,qq=123]", "ban", Args{"user": "123", "duration": 10 * time.Minute}, false},
+		{"  /ban 123 1h  ", "ban", Args{"user": "123", "duration": time.Hour}, false},
+		{"/ban", "ban", nil, true},
+		{"/ban abc", "ban", nil, true},
+		{"/ban 123 -5m", "ban", nil, true},
+		{"/ban 123 5m extra", "ban", nil, true},
+		{`/say  hello   "world"  [CQ:face,id=1]`, "say", Args{"text": `hello   "world"  [CQ:face,id=1]`}, false},
+		{"/说 你好", "say", Args{"text": "你好"}, false},
+		{"/say", "say", nil, true},
+		{"/mode ON", "mode", Args{"mode": "on"}, false},
+		{"/mode maybe", "mode", nil, true},
+		{"/help", "help", Args{}, false},
+		{"/unknown", "", nil, false},
+		{"ban 123", "", nil, false},
+		{"/", "", nil, false},
+	}
+	for _, tt := range tests {
+		cmd, args, err := r.Parse(tt.text)
+		name := ""
+		if cmd != nil {
+			name = cmd.Name
+		}
+		if name != tt.cmd {
+			t.Errorf("Parse(%q) command = %q, want %q", tt.text, name, tt.cmd)
+			continue
+		}
+		var usage *UsageError
+		if errors.As(err, &usage) != tt.usage {
+			t.Errorf("Parse(%q) error = %v, want usage error %v", tt.text, err, tt.usage)
+			continue
+		}
+		if !tt.usage && !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("Parse(%q) args = %v, want %v", tt.text, args, tt.args)
+		}
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		text string
+		want []argToken
+	}{
+		{"", nil},
+		{"a  b", []argToken{{"a", 4}, {"b", 1}}},
+		{`a "b c" [CQ:at,qq=1] d`, []argToken{{"a", 22}, {"b c", 20}, {"[CQ:at,qq=1]", 14}, {"d", 1}}},
+		{"[CQ:share,title=a b] x", []argToken{{"[CQ:share,title=a b]", 22}, {"x", 1}}},
+		{`"ab c`, []argToken{{`"ab`, 5}, {"c", 1}}},
+		{`"" x`, []argToken{{"", 4}, {"x", 1}}},
+		{"甲　乙", []argToken{{"甲", 9}, {"乙", 3}}},
+	}
+	for _, tt := range tests {
+		if got := splitArgs(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitArgs(%q) = %+v, want %+v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+		ok   bool
+	}{
+		{"30", 30 * time.Second, true},
+		{"30s", 30 * time.Second, true},
+		{" 5m ", 5 * time.Minute, true},
+		{"1.5h", 90 * time.Minute, true},
+		{"2d", 48 * time.Hour, true},
+		{"1w", 7 * 24 * time.Hour, true},
+		{"500ms", 500 * time.Millisecond, true},
+		{"10分钟", 10 * time.Minute, true},
+		{"3 天", 72 * time.Hour, true},
+		{"1h30m", 90 * time.Minute, true},
+		{"0", 0, true},
+		{"-5m", 0, false},
+		{"-1h30m", 0, false},
+		{"abc", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseDuration(%q) = %v, %v, want %v, ok %v", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseUser(t *testing.T) {
+	openid := "E8A5F3C1D2B4A6978877665544332211"
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"123", "123", true},
+		{"@123", "123", true},
+		{"[CQ:at,qq=123]", "123", true},
+		{"[CQ:at,qq=123,name=abc]", "123", true},
+		{"wxid_ab-12", "wxid_ab-12", true},
+		{"@wxid_ab-12", "wxid_ab-12", true},
+		{openid, openid, true},
+		{"@" + openid, openid, true},
+		{"@some_user", "@some_user", true},
+		{"some_user", "", false},
+		{"@abc", "", false},
+		{"@1abcde", "", false},
+		{"hello", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, err := ParseUser(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseUser(%q) = %q, %v, want %q, ok %v", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/**
+ * @description: cron表达式，格式为"分 时 日 月 周"，支持*、列表、范围和步长，周日可写作0或7
+ */
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+/**
+ * @description: 解析cron表达式
+ * @param {string} spec 如"0 9 * * 1-5"、"30 8 1 * *"
+ * @return {*Cron}
+ */
+func ParseCron(spec string) (*Cron, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron表达式应包含5个字段: %s", spec)
+	}
+	c := &Cron{}
+	var err error
+	if c.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, err
+	}
+	if c.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, err
+	}
+	if c.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, err
+	}
+	if c.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, err
+	}
+	if c.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, err
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	// 与常见cron实现一致，以*或?开头的日、周字段（如*/2）视为不限定
+	c.domAny = strings.HasPrefix(fields[2], "*") || strings.HasPrefix(fields[2], "?")
+	c.dowAny = strings.HasPrefix(fields[4], "*") || strings.HasPrefix(fields[4], "?")
+	return c, nil
+}
+
+// 解析单个字段为位图
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("无效的cron步长: %s", part)
+			}
+			rng = part[:i]
+		}
+		lo, hi := min, max
+		if rng != "*" && rng != "?" {
+			var err error
+			if a, b, ok := strings.Cut(rng, "-"); ok {
+				if lo, err = strconv.Atoi(a); err != nil {
+					return 0, fmt.Errorf("无效的cron字段: %s", part)
+				}
+				if hi, err = strconv.Atoi(b); err != nil {
+					return 0, fmt.Errorf("无效的cron字段: %s", part)
+				}
+			} else {
+				if lo, err = strconv.Atoi(rng); err != nil {
+					return 0, fmt.Errorf("无效的cron字段: %s", part)
+				}
+				hi = lo
+				if strings.Contains(part, "/") {
+					hi = max
+				}
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("cron字段超出范围%d-%d: %s", min, max, part)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+/**
+ * @description: 计算t之后的下一次执行时间，五年内无匹配时返回零值
+ * @param {time.Time} t 起始时间
+ * @return {time.Time}
+ */
+func (c *Cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// 日与周都限定时满足其一即可，任一字段以*开头时需同时满足
+func (c *Cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+	} {
+		if _, err := ParseCron(spec); err == nil {
+			t.Errorf("ParseCron(%q) expected error", spec)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	loc := time.UTC
+	at := func(s string) time.Time {
+		v, err := time.ParseInLocation("2006-01-02 15:04", s, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	// 2024-01-01为周一
+	tests := []struct {
+		name string
+		spec string
+		from string
+		want string
+	}{
+		{"每分钟", "* * * * *", "2024-01-01 10:00", "2024-01-01 10:01"},
+		{"步长", "*/15 * * * *", "2024-01-01 10:16", "2024-01-01 10:30"},
+		{"带起点的步长", "5/20 * * * *", "2024-01-01 10:26", "2024-01-01 10:45"},
+		{"范围", "0 9-11 * * *", "2024-01-01 11:30", "2024-01-02 09:00"},
+		{"列表", "0 8,20 * * *", "2024-01-01 08:00", "2024-01-01 20:00"},
+		{"跨年", "0 0 1 1 *", "2024-06-01 00:00", "2025-01-01 00:00"},
+		{"工作日", "0 9 * * 1-5", "2024-01-05 10:00", "2024-01-08 09:00"},
+		{"周日写作0", "0 9 * * 0", "2024-01-01 00:00", "2024-01-07 09:00"},
+		{"周日写作7", "0 9 * * 7", "2024-01-01 00:00", "2024-01-07 09:00"},
+		{"日与周满足其一", "0 0 15 * 5", "2024-01-01 00:00", "2024-01-05 00:00"},
+		{"日与周满足其一（日先到）", "0 0 3 * 5", "2024-01-01 00:00", "2024-01-03 00:00"},
+		{"只限定日", "0 0 31 * *", "2024-02-01 00:00", "2024-03-31 00:00"},
+		{"日为步长时与周同时满足", "0 0 */2 * 1", "2024-01-01 00:00", "2024-01-15 00:00"},
+		{"周为步长时与日同时满足", "0 0 2 * */2", "2024-01-01 00:00", "2024-01-02 00:00"},
+		{"闰日", "0 0 29 2 *", "2025-01-01 00:00", "2028-02-29 00:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseCron(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseCron(%q): %v", tt.spec, err)
+			}
+			if got := c.Next(at(tt.from)); !got.Equal(at(tt.want)) {
+				t.Errorf("Next(%s) = %s, want %s", tt.from, got.Format("2006-01-02 15:04"), tt.want)
+			}
+		})
+	}
+}
+
+func TestCronNextTruncatesSeconds(t *testing.T) {
+	c, _ := ParseCron("* * * * *")
+	from := time.Date(2024, 1, 1, 10, 0, 42, 0, time.UTC)
+	if got, want := c.Next(from), time.Date(2024, 1, 1, 10, 1, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Next(%s) = %s, want %s", from, got, want)
+	}
+}
+
+func TestCronNextNeverOccurs(t *testing.T) {
+	for _, spec := range []string{"0 0 31 4 *", "0 0 30 2 *"} {
+		c, err := ParseCron(spec)
+		if err != nil {
+			t.Fatalf("ParseCron(%q): %v", spec, err)
+		}
+		if got := c.Next(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)); !got.IsZero() {
+			t.Errorf("Next for %q = %s, want zero time", spec, got)
+		}
+	}
+}
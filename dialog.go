@@ -0,0 +1,214 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrTimeout  = errors.New("等待用户输入超时")
+	ErrCanceled = errors.New("用户已取消")
+)
+
+/**
+ * @description: 默认的取消关键词
+ */
+var CancelWords = []string{"取消", "cancel"}
+
+/**
+ * @description: 对话步骤
+ */
+type Step struct {
+	Name     string                                        // 步骤名称，用户输入以此为键保存在DialogState.Values中
+	Prompt   string                                        // 提示语
+	Validate func(input string) error                      // 可选，校验输入，返回的错误信息回复给用户后重新等待输入
+	Next     func(input string, state *DialogState) string // 可选，返回下一步名称，返回空字符串时结束对话；为nil时按顺序进入下一步
+}
+
+/**
+ * @description: 多轮对话，每步完成后将进度保存到数据桶，插件重启后再次Run时从中断处继续
+ */
+type Dialog struct {
+	Name        string   // 对话名称，同一用户同时只能进行一个同名对话
+	Steps       []Step   // 对话步骤，从第一步开始
+	Timeout     int      // 每步等待超时，单位：毫秒，默认60000
+	CancelWords []string // 取消关键词，默认使用CancelWords
+	Bucket      string   // 保存进度的数据桶，默认"dialog"
+}
+
+/**
+ * @description: 对话进度
+ */
+type DialogState struct {
+	Dialog string            `json:"dialog"`
+	Step   string            `json:"step"`
+	Values map[string]string `json:"values"`
+}
+
+/**
+ * @description: 运行对话，超时返回ErrListenTimeout并保留进度，取消返回ErrCanceled并清除进度
+ * @param {*Sender} s 当前用户
+ * @return {*DialogState} 对话完成时的进度，包含每步的输入
+ */
+func (d *Dialog) Run(s *Sender) (*DialogState, error) {
+	if err := d.validate(); err != nil {
+		return nil, err
+	}
+	key := d.key(s)
+	state := d.load(key)
+	index := d.index(state.Step)
+	if index < 0 {
+		index = 0
+	}
+	for index < len(d.Steps) {
+		step := d.Steps[index]
+		state.Step = step.Name
+		if err := d.save(key, state); err != nil {
+			return state, err
+		}
+		input, err := d.ask(s, step)
+		if err != nil {
+			if errors.Is(err, ErrCanceled) {
+				BucketDelete(d.bucket(), key)
+			}
+			return state, err
+		}
+		state.Values[step.Name] = input
+		if step.Next == nil {
+			index++
+			continue
+		}
+		next := step.Next(input, state)
+		if next == "" {
+			break
+		}
+		if index = d.index(next); index < 0 {
+			return state, fmt.Errorf("对话%s不存在步骤%s", d.Name, next)
+		}
+	}
+	return state, BucketDelete(d.bucket(), key)
+}
+
+/**
+ * @description: 放弃用户在该对话中保存的进度
+ * @param {*Sender} s 当前用户
+ */
+func (d *Dialog) Reset(s *Sender) error {
+	return BucketDelete(d.bucket(), d.key(s))
+}
+
+// 发送提示语并等待通过校验的输入
+func (d *Dialog) ask(s *Sender, step Step) (string, error) {
+	if step.Prompt != "" {
+		if _, err := s.Reply(step.Prompt); err != nil {
+			return "", err
+		}
+	}
+	for {
+		result, err := s.ListenMessage(context.Background(), ListenOptions{Timeout: time.Duration(d.timeout()) * time.Millisecond})
+		if err != nil {
+			return "", err
+		}
+		input := result.Text
+		if IsCancel(input, d.CancelWords...) {
+			return "", ErrCanceled
+		}
+		if step.Validate == nil {
+			return input, nil
+		}
+		invalid := step.Validate(input)
+		if invalid == nil {
+			return input, nil
+		}
+		if _, err := s.Reply(invalid.Error()); err != nil {
+			return "", err
+		}
+	}
+}
+
+// 步骤名称用于恢复进度和保存输入，必须非空且互不相同
+func (d *Dialog) validate() error {
+	if len(d.Steps) == 0 {
+		return fmt.Errorf("对话%s没有步骤", d.Name)
+	}
+	names := map[string]bool{}
+	for i, step := range d.Steps {
+		if step.Name == "" {
+			return fmt.Errorf("对话%s的第%d个步骤没有名称", d.Name, i+1)
+		}
+		if names[step.Name] {
+			return fmt.Errorf("对话%s的步骤名称%s重复", d.Name, step.Name)
+		}
+		names[step.Name] = true
+	}
+	return nil
+}
+
+func (d *Dialog) index(name string) int {
+	for i, step := range d.Steps {
+		if step.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (d *Dialog) timeout() int {
+	if d.Timeout > 0 {
+		return d.Timeout
+	}
+	return 60000
+}
+
+func (d *Dialog) bucket() string {
+	if d.Bucket != "" {
+		return d.Bucket
+	}
+	return "dialog"
+}
+
+func (d *Dialog) key(s *Sender) string {
+	return d.Name + ":" + s.GetChatID() + ":" + s.GetUserID()
+}
+
+func (d *Dialog) load(key string) *DialogState {
+	state := &DialogState{}
+	if data := BucketGet(d.bucket(), key); data != "" {
+		json.Unmarshal([]byte(data), state)
+	}
+	if state.Dialog != d.Name {
+		state = &DialogState{Dialog: d.Name}
+	}
+	if state.Values == nil {
+		state.Values = map[string]string{}
+	}
+	return state
+}
+
+func (d *Dialog) save(key string, state *DialogState) error {
+	data, _ := json.Marshal(state)
+	return BucketSet(d.bucket(), key, string(data))
+}
+
+/**
+ * @description: 判断用户输入是否为取消关键词，不区分大小写
+ * @param {string} input 用户输入
+ * @param {...string} words 取消关键词，为空时使用CancelWords
+ * @return {bool}
+ */
+func IsCancel(input string, words ...string) bool {
+	if len(words) == 0 {
+		words = CancelWords
+	}
+	input = strings.TrimSpace(input)
+	for _, w := range words {
+		if strings.EqualFold(input, w) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/beego/beego/v2/client/httplib"
+	"github.com/buger/jsonparser"
+)
+
+/**
+ * @description: 编辑已发送的文本消息，不支持编辑的平台发送新消息后撤回原消息
+ * @param {string} messageid 消息ID
+ * @param {string} text 新的文本内容
+ * @return {[]string} 编辑后的消息ID，重新发送时为新消息ID，撤回原消息失败时同时返回新消息ID与错误
+ */
+func (s *Sender) EditMessage(messageid, text string) ([]string, error) {
+	return s.edit(messageid, "text", text, s.Reply)
+}
+
+/**
+ * @description: 编辑已发送的markdown消息，不支持编辑的平台发送新消息后撤回原消息
+ * @param {string} messageid 消息ID
+ * @param {string} text 新的markdown字符串
+ * @return {[]string} 编辑后的消息ID，重新发送时为新消息ID，撤回原消息失败时同时返回新消息ID与错误
+ */
+func (s *Sender) EditMarkdown(messageid, text string) ([]string, error) {
+	return s.edit(messageid, "markdown", text, s.ReplyMarkdown)
+}
+
+func (s *Sender) edit(messageid, key, text string, resend func(string) ([]string, error)) ([]string, error) {
+	if !PlatformOf(s.GetImtype()).Edit {
+		// 先发送新消息，发送失败时保留原消息
+		ids, err := resend(text)
+		if err != nil {
+			return nil, err
+		}
+		return ids, s.RecallMessage(messageid)
+	}
+	params := map[string]interface{}{
+		"senderid":  s.SenderID,
+		"messageid": messageid,
+		key:         text,
+	}
+	body, _ := json.Marshal(params)
+	resp, err := httplib.Post(sockUrl()+"/editMessage").Header("Content-Type", "application/json").Body(body).SetTransport(transport).Bytes()
+	if err != nil {
+		return nil, err
+	}
+	if ok, err := jsonparser.GetBoolean(resp, "data"); err == nil && !ok {
+		return nil, errors.New("编辑失败")
+	}
+	return []string{messageid}, nil
+}
@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// 事件类型
+const (
+	EventMessage  = "message"
+	EventCallback = "callback"
+	EventRequest  = "request"
+)
+
+/**
+ * @description: 消息监听器推送的事件，可通过类型断言或type switch得到具体事件
+ */
+type Event interface {
+	EventType() string
+}
+
+/**
+ * @description: 聊天消息事件，监听器只推送消息原文，不包含发送者等信息
+ */
+type MessageEvent struct {
+	Raw     string // 监听器推送的原始内容
+	Content string // 消息内容，已将转义的换行还原
+}
+
+func (e *MessageEvent) EventType() string { return EventMessage }
+
+/**
+ * @description: 按钮回调事件，Data为被点击按钮的回调数据
+ */
+type CallbackEvent struct {
+	Imtype    string `json:"imtype"`
+	ChatID    string `json:"chatid"`
+	UserID    string `json:"userid"`
+	MessageID string `json:"messageid"`
+	Data      string `json:"data"`
+}
+
+func (e *CallbackEvent) EventType() string { return EventCallback }
+
+// 请求类型
+const (
+	RequestFriend = "friend"
+	RequestJoin   = "join"
+	RequestInvite = "invite"
+)
+
+/**
+ * @description: 好友申请、入群申请、入群邀请事件，使用ApproveRequest、RejectRequest处理
+ */
+type RequestEvent struct {
+	Kind    string `json:"kind"` // friend/join/invite
+	Imtype  string `json:"imtype"`
+	ChatID  string `json:"chatid"`
+	UserID  string `json:"userid"`
+	Comment string `json:"comment"` // 验证信息或入群问题的回答
+	Flag    string `json:"flag"`    // 请求标识
+}
+
+func (e *RequestEvent) EventType() string { return EventRequest }
+
+/**
+ * @description: 解析监听器推送的内容。事件类型只取自服务端SSE的event行，不解析消息正文，
+ * 用户发送的json文本不会被当作事件；未知类型或解析失败时均视为聊天消息
+ * @param {string} event SSE的event行声明的事件类型，聊天消息为空或为"message"
+ * @param {string} data SSE的data内容
+ * @return {Event}
+ */
+func ParseEvent(event, data string) Event {
+	if factory, ok := eventTypes[event]; ok {
+		e := factory()
+		if json.Unmarshal([]byte(data), e) == nil {
+			return e
+		}
+	}
+	content := strings.ReplaceAll(data, "\\n", "\n")
+	return &MessageEvent{Raw: data, Content: content}
+}
+
+// 事件类型到事件结构体的映射
+var eventTypes = map[string]func() Event{
+	EventCallback:    func() Event { return &CallbackEvent{} },
+	EventRequest:     func() Event { return &RequestEvent{} },
+	EventMemberJoin:  func() Event { return &MemberJoinEvent{} },
+	EventMemberLeave: func() Event { return &MemberLeaveEvent{} },
+	EventRecall:      func() Event { return &RecallEvent{} },
+	EventMute:        func() Event { return &MuteEvent{} },
+	EventAdminChange: func() Event { return &AdminChangeEvent{} },
+	EventPoke:        func() Event { return &PokeEvent{} },
+}
+
+/**
+ * @description: 事件过滤器，返回false的事件不会交给监听句柄
+ */
+type EventFilter func(Event) bool
+
+/**
+ * @description: 只接收指定类型的事件
+ * @param {...string} types 事件类型，如EventMessage、EventMemberJoin
+ * @return {EventFilter}
+ */
+func OnlyEvents(types ...string) EventFilter {
+	return func(e Event) bool {
+		return containsString(types, e.EventType())
+	}
+}
+
+/**
+ * @description: 添加事件监听句柄，按服务端声明的事件类型解析事件
+ * @param {string} chatid 群组ID
+ * @param {string} userid 用户ID
+ * @param {func(Event)} function 事件监听句柄，回调函数
+ * @param {...EventFilter} filters 可选，事件过滤器，全部通过的事件才会交给监听句柄
+ */
+func AddEventListener(imtype, chatid, userid string, exitChannel chan struct{}, function func(Event), filters ...EventFilter) {
+	msghook(imtype, chatid, userid, exitChannel, func(event, data string) {
+		e := ParseEvent(event, data)
+		for _, filter := range filters {
+			if !filter(e) {
+				return
+			}
+		}
+		function(e)
+	})
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/beego/beego/v2/client/httplib"
+	"github.com/buger/jsonparser"
+)
+
+/**
+ * @description: 回复文件
+ * @param {string} name 文件名，如report.csv
+ * @param {Media} src 文件来源，支持链接、本地文件、字节和io.Reader
+ * @return {[]string} 消息ID
+ */
+func (s *Sender) ReplyFile(name string, src Media) ([]string, error) {
+	if src.Name == "" {
+		src.Name = name
+	}
+	fileurl, err := s.mediaPayload(MediaDocument, src)
+	if err != nil {
+		return nil, err
+	}
+	params := map[string]interface{}{
+		"senderid": s.SenderID,
+		"fileurl":  fileurl,
+		"filename": name,
+	}
+	body, _ := json.Marshal(params)
+	var msgIds []string
+	if resp, err := httplib.Post(sockUrl()+"/sendFile").Header("Content-Type", "application/json").Body(body).SetTransport(transport).Bytes(); err == nil {
+		if data, err := jsonparser.GetUnsafeString(resp, "data"); err == nil {
+			json.Unmarshal([]byte(data), &msgIds)
+			return msgIds, nil
+		}
+	}
+	return nil, errors.New("回复失败")
+}
+
+/**
+ * @description: 推送文件
+ * @param {string} imtType 包括：qq/qb/wx/wb/tg/tb/wxmp/wxsv
+ * @param {string} groupCode 群号
+ * @param {string} userID 用户ID
+ * @param {string} name 文件名
+ * @param {Media} src 文件来源，支持链接、本地文件、字节和io.Reader
+ */
+func PushFile(imType, groupCode, userID, name string, src Media) error {
+	if p, ok := Platforms[imType]; ok && !p.File {
+		return fmt.Errorf("%w: %s不支持%s", ErrMediaUnsupported, imType, MediaDocument)
+	}
+	if src.Name == "" {
+		src.Name = name
+	}
+	fileurl, err := src.Payload(MediaDocument)
+	if err != nil {
+		return err
+	}
+	params := map[string]interface{}{
+		"imType":    imType,
+		"groupCode": groupCode,
+		"userID":    userID,
+		"fileurl":   fileurl,
+		"filename":  name,
+	}
+	body, _ := json.Marshal(params)
+	_, err = httplib.Post(sockUrl()+"/pushFile").Header("Content-Type", "application/json").Body(body).SetTransport(transport).Bytes()
+	return err
+}
@@ -0,0 +1,195 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/beego/beego/v2/client/httplib"
+	"github.com/buger/jsonparser"
+)
+
+var (
+	ErrNotAdmin          = errors.New("当前用户没有群管理权限")
+	ErrBotNotAdmin       = errors.New("机器人不是群管理员")
+	ErrTargetOwner       = errors.New("不能对群主执行该操作")
+	ErrUnsupportedImtype = errors.New("当前平台不支持群管理")
+	ErrNotGroup          = errors.New("当前会话不是群聊")
+	ErrBanDuration       = errors.New("禁言时长不能少于1秒")
+)
+
+// 群成员角色
+const (
+	GroupRoleOwner  = "owner"
+	GroupRoleAdmin  = "admin"
+	GroupRoleMember = "member"
+)
+
+/**
+ * @description: 群管理句柄，每次操作前检查调用者与机器人的权限
+ */
+type Group struct {
+	sender *Sender
+	ChatID string
+	Imtype string
+}
+
+/**
+ * @description: 获取当前会话的群管理句柄
+ * @return {*Group}
+ */
+func (s *Sender) Group() (*Group, error) {
+	imtype := s.GetImtype()
+	if !PlatformOf(imtype).Group {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedImtype, imtype)
+	}
+	chatID := s.GetChatID()
+	if chatID == "" || chatID == "0" {
+		return nil, ErrNotGroup
+	}
+	return &Group{sender: s, ChatID: chatID, Imtype: imtype}, nil
+}
+
+/**
+ * @description: 获取群成员角色，受GroupCacheTTL缓存
+ * @param {string} userid 用户ID
+ * @return {string} owner/admin/member
+ */
+func (g *Group) MemberRole(userid string) string {
+	member, err := g.sender.groupMemberInfo(g.Imtype, g.ChatID, userid)
+	if err != nil {
+		return ""
+	}
+	return member.Role
+}
+
+/**
+ * @description: 获取机器人在群内的角色
+ * @return {string} owner/admin/member
+ */
+func (g *Group) BotRole() string {
+	params := map[string]interface{}{
+		"senderid": g.sender.SenderID,
+		"chatid":   g.ChatID,
+	}
+	body, _ := json.Marshal(params)
+	resp, _ := httplib.Post(sockUrl()+"/getBotRole").Header("Content-Type", "application/json").Body(body).SetTransport(transport).Bytes()
+	rlt, _ := jsonparser.GetString(resp, "data")
+	return rlt
+}
+
+// 检查调用者为autMan管理员、拥有群管理权限，或为群主、群管理员
+func (g *Group) checkCaller() error {
+	if g.sender.Can(PermGroupManage) {
+		return nil
+	}
+	if role := g.MemberRole(g.sender.GetUserID()); role != GroupRoleOwner && role != GroupRoleAdmin {
+		return ErrNotAdmin
+	}
+	return nil
+}
+
+// 在checkCaller基础上检查机器人为群主或群管理员
+func (g *Group) check() error {
+	if err := g.checkCaller(); err != nil {
+		return err
+	}
+	if role := g.BotRole(); role != GroupRoleOwner && role != GroupRoleAdmin {
+		return ErrBotNotAdmin
+	}
+	return nil
+}
+
+// 在check基础上检查目标用户不是群主
+func (g *Group) checkTarget(userid string) error {
+	if err := g.check(); err != nil {
+		return err
+	}
+	if g.MemberRole(userid) == GroupRoleOwner {
+		return ErrTargetOwner
+	}
+	return nil
+}
+
+/**
+ * @description: 踢出群成员
+ * @param {string} userid 用户ID
+ */
+func (g *Group) Kick(userid string) error {
+	if err := g.checkTarget(userid); err != nil {
+		return err
+	}
+	return g.sender.GroupKick(userid)
+}
+
+/**
+ * @description: 禁言群成员
+ * @param {string} userid 用户ID
+ * @param {time.Duration} duration 禁言时长，精确到秒，少于1秒时返回ErrBanDuration，解除禁言请使用Unban
+ */
+func (g *Group) Ban(userid string, duration time.Duration) error {
+	if duration < time.Second {
+		return ErrBanDuration
+	}
+	if err := g.checkTarget(userid); err != nil {
+		return err
+	}
+	return g.sender.GroupBan(userid, int(duration/time.Second))
+}
+
+/**
+ * @description: 解除群成员禁言
+ * @param {string} userid 用户ID
+ */
+func (g *Group) Unban(userid string) error {
+	if err := g.check(); err != nil {
+		return err
+	}
+	return g.sender.GroupUnban(userid)
+}
+
+/**
+ * @description: 开启全员禁言，duration大于0时到期自动解除
+ * @param {time.Duration} duration 禁言时长，为0时不自动解除
+ * @return {*MuteState} 禁言后的状态
+ */
+func (g *Group) Mute(duration time.Duration) (*MuteState, error) {
+	if err := g.check(); err != nil {
+		return nil, err
+	}
+	return GroupMute(g.Imtype, g.ChatID, duration)
+}
+
+/**
+ * @description: 解除全员禁言
+ * @return {*MuteState} 解除后的状态
+ */
+func (g *Group) Unmute() (*MuteState, error) {
+	if err := g.check(); err != nil {
+		return nil, err
+	}
+	return GroupUnmute(g.Imtype, g.ChatID)
+}
+
+/**
+ * @description: 发送群公告
+ * @param {string} notice 公告内容
+ */
+func (g *Group) Notice(notice string) error {
+	if err := g.check(); err != nil {
+		return err
+	}
+	return g.sender.GroupNoticeSend(notice)
+}
+
+/**
+ * @description: 邀请好友入群
+ * @param {string} friend 好友ID
+ */
+func (g *Group) Invite(friend string) error {
+	if err := g.checkCaller(); err != nil {
+		return err
+	}
+	return g.sender.GroupInviteIn(friend, g.ChatID)
+}
@@ -0,0 +1,204 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/v2/client/httplib"
+	"github.com/buger/jsonparser"
+)
+
+/**
+ * @description: 群成员与群信息的缓存时长，为0时不缓存
+ */
+var GroupCacheTTL time.Duration
+
+var groupCache = struct {
+	sync.Mutex
+	items map[string]groupCacheItem
+}{items: map[string]groupCacheItem{}}
+
+type groupCacheItem struct {
+	data    []byte
+	expires time.Time
+}
+
+/**
+ * @description: 群成员信息
+ */
+type GroupMember struct {
+	UserID   string `json:"userid"`
+	Nickname string `json:"nickname"`
+	Card     string `json:"card"`     // 群名片
+	Role     string `json:"role"`     // owner/admin/member
+	JoinTime int64  `json:"jointime"` // 入群时间，unix时间戳，单位：秒
+}
+
+/**
+ * @description: 入群时间
+ * @return {time.Time}
+ */
+func (m GroupMember) JoinedAt() time.Time {
+	return time.Unix(m.JoinTime, 0)
+}
+
+/**
+ * @description: 群信息
+ */
+type GroupInfo struct {
+	ChatID      string `json:"chatid"`
+	Name        string `json:"name"`
+	MemberCount int    `json:"membercount"`
+	OwnerID     string `json:"owner"`
+}
+
+/**
+ * @description: 分页获取群成员列表
+ * @param {string} chatID 群组ID
+ * @param {int} page 页码，从1开始
+ * @param {int} size 每页数量
+ * @return {[]GroupMember}
+ */
+func (s *Sender) GroupMembers(chatID string, page, size int) ([]GroupMember, error) {
+	if page < 1 {
+		page = 1
+	}
+	params := map[string]interface{}{
+		"senderid": s.SenderID,
+		"chatid":   chatID,
+		"page":     page,
+		"size":     size,
+	}
+	var members []GroupMember
+	if err := groupQuery(s.GetImtype(), "/groupMembers", params, &members); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+/**
+ * @description: 获取群全部成员
+ * @param {string} chatID 群组ID
+ * @return {[]GroupMember}
+ */
+func (s *Sender) AllGroupMembers(chatID string) ([]GroupMember, error) {
+	const size = 200
+	var all []GroupMember
+	for page := 1; ; page++ {
+		members, err := s.GroupMembers(chatID, page, size)
+		if err != nil {
+			return all, err
+		}
+		all = append(all, members...)
+		if len(members) < size {
+			return all, nil
+		}
+	}
+}
+
+/**
+ * @description: 获取当前群内指定成员的信息
+ * @param {string} userID 用户ID
+ * @return {*GroupMember}
+ */
+func (s *Sender) GroupMemberInfo(userID string) (*GroupMember, error) {
+	return s.groupMemberInfo(s.GetImtype(), s.GetChatID(), userID)
+}
+
+func (s *Sender) groupMemberInfo(imtype, chatID, userID string) (*GroupMember, error) {
+	params := map[string]interface{}{
+		"senderid": s.SenderID,
+		"chatid":   chatID,
+		"userid":   userID,
+	}
+	member := &GroupMember{}
+	if err := groupQuery(imtype, "/groupMemberInfo", params, member); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+/**
+ * @description: 获取群信息
+ * @param {string} chatID 群组ID
+ * @return {*GroupInfo}
+ */
+func (s *Sender) GroupInfo(chatID string) (*GroupInfo, error) {
+	params := map[string]interface{}{
+		"senderid": s.SenderID,
+		"chatid":   chatID,
+	}
+	info := &GroupInfo{}
+	if err := groupQuery(s.GetImtype(), "/groupInfo", params, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+/**
+ * @description: 获取机器人所在的群列表
+ * @return {[]GroupInfo}
+ */
+func (s *Sender) BotGroups() ([]GroupInfo, error) {
+	params := map[string]interface{}{
+		"senderid": s.SenderID,
+	}
+	var groups []GroupInfo
+	if err := groupQuery("", "/botGroups", params, &groups); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+/**
+ * @description: 清空群成员与群信息缓存
+ */
+func ClearGroupCache() {
+	groupCache.Lock()
+	groupCache.items = map[string]groupCacheItem{}
+	groupCache.Unlock()
+}
+
+// 请求群查询接口并解析data中的json字符串，GroupCacheTTL大于0时缓存按群查询的结果
+// 缓存键由平台、群号等组成而不含senderid，同一群的查询在不同消息之间共享缓存
+func groupQuery(imtype, path string, params map[string]interface{}, v interface{}) error {
+	body, _ := json.Marshal(params)
+	_, byChat := params["chatid"]
+	cached := GroupCacheTTL > 0 && byChat
+	key := fmt.Sprint(imtype, "|", path, "|", params["chatid"], "|", params["userid"], "|", params["page"], "|", params["size"])
+	if cached {
+		groupCache.Lock()
+		item, ok := groupCache.items[key]
+		groupCache.Unlock()
+		if ok && time.Now().Before(item.expires) {
+			return json.Unmarshal(item.data, v)
+		}
+	}
+	resp, err := httplib.Post(sockUrl()+path).Header("Content-Type", "application/json").Body(body).SetTransport(transport).Bytes()
+	if err != nil {
+		return err
+	}
+	data, err := jsonparser.GetUnsafeString(resp, "data")
+	if err != nil || strings.TrimSpace(data) == "" {
+		return errors.New("查询群信息失败")
+	}
+	if err := json.Unmarshal([]byte(data), v); err != nil {
+		return err
+	}
+	if cached {
+		now := time.Now()
+		groupCache.Lock()
+		for k, item := range groupCache.items {
+			if now.After(item.expires) {
+				delete(groupCache.items, k)
+			}
+		}
+		groupCache.items[key] = groupCacheItem{data: []byte(data), expires: now.Add(GroupCacheTTL)}
+		groupCache.Unlock()
+	}
+	return nil
+}
@@ -0,0 +1,276 @@
+// Package header 解析、生成和校验autMan插件的头注，如：
+//
+//	//[title: 天气]
+//	//[version: 1.0.0]
+//	//[rule: ^天气 (?P<city>\S+)$]
+//	//[cron: 0 8 * * *]
+//	//[admin: false]
+//	//[imType: qq,wx]
+package header
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hdbjlizhe/middleware"
+)
+
+// 头注字段名称
+const (
+	KeyTitle       = "title"
+	KeyVersion     = "version"
+	KeyAuthor      = "author"
+	KeyClass       = "class"
+	KeyDescription = "description"
+	KeyRule        = "rule"
+	KeyCron        = "cron"
+	KeyAdmin       = "admin"
+	KeyDisable     = "disable"
+	KeyPriority    = "priority"
+	KeyImType      = "imType"
+	KeyPublic      = "public"
+)
+
+var linePattern = regexp.MustCompile(`^\s*(?://|#)\s*\[(\w+)\s*:\s*(.*)\]\s*$`)
+
+/**
+ * @description: 未识别的头注字段，按原顺序保留
+ */
+type Field struct {
+	Key   string
+	Value string
+}
+
+/**
+ * @description: 插件头注
+ */
+type Header struct {
+	Title       string // 插件名称，GetPluginName读取的值
+	Version     string // 插件版本，GetPluginVersion读取的值
+	Author      string
+	Class       string
+	Description string
+	Rules       []string // 触发规则，可有多条
+	Crons       []string // 定时规则，可有多条
+	Admin       bool     // 是否仅管理员可触发
+	Disable     bool     // 是否禁用
+	Priority    int      // 优先级，数值越大越先匹配
+	ImTypes     []string // 允许触发的平台，为空时不限制
+	Public      bool     // 是否公开到插件市场
+	Extra       []Field
+}
+
+/**
+ * @description: 解析插件源码开头的头注，遇到第一行非注释内容时停止
+ * @param {string} src 插件源码
+ * @return {*Header}
+ */
+func Parse(src string) (*Header, error) {
+	h := &Header{}
+	scanner := bufio.NewScanner(strings.NewReader(src))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#!") {
+			continue
+		}
+		if !strings.HasPrefix(line, "//") && !strings.HasPrefix(line, "#") {
+			break
+		}
+		m := linePattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		if err := h.set(m[1], strings.TrimSpace(m[2])); err != nil {
+			return nil, fmt.Errorf("第%d行: %w", n, err)
+		}
+	}
+	return h, scanner.Err()
+}
+
+/**
+ * @description: 读取并解析插件文件的头注
+ * @param {string} path 插件文件路径
+ * @return {*Header}
+ */
+func ParseFile(path string) (*Header, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(string(data))
+}
+
+func (h *Header) set(key, value string) error {
+	var err error
+	switch key {
+	case KeyTitle:
+		h.Title = value
+	case KeyVersion:
+		h.Version = value
+	case KeyAuthor:
+		h.Author = value
+	case KeyClass:
+		h.Class = value
+	case KeyDescription:
+		h.Description = value
+	case KeyRule:
+		h.Rules = append(h.Rules, value)
+	case KeyCron:
+		h.Crons = append(h.Crons, value)
+	case KeyAdmin:
+		h.Admin, err = parseBool(key, value)
+	case KeyDisable:
+		h.Disable, err = parseBool(key, value)
+	case KeyPublic:
+		h.Public, err = parseBool(key, value)
+	case KeyPriority:
+		if value != "" {
+			if h.Priority, err = strconv.Atoi(value); err != nil {
+				err = fmt.Errorf("%s应为整数: %s", key, value)
+			}
+		}
+	case KeyImType:
+		h.ImTypes = append(h.ImTypes, strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '，' || r == ' ' })...)
+	default:
+		h.Extra = append(h.Extra, Field{Key: key, Value: value})
+	}
+	return err
+}
+
+func parseBool(key, value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s应为true或false: %s", key, value)
+	}
+	return b, nil
+}
+
+/**
+ * @description: 生成头注，每个字段一行，prefix为注释符号，默认"//"
+ * @param {string} prefix 可选，注释符号，如Python插件使用"#"
+ * @return {string}
+ */
+func (h *Header) Format(prefix ...string) string {
+	p := "//"
+	if len(prefix) > 0 && prefix[0] != "" {
+		p = prefix[0]
+	}
+	var sb strings.Builder
+	write := func(key, value string) {
+		sb.WriteString(p + "[" + key + ": " + value + "]\n")
+	}
+	write(KeyTitle, h.Title)
+	if h.Version != "" {
+		write(KeyVersion, h.Version)
+	}
+	if h.Author != "" {
+		write(KeyAuthor, h.Author)
+	}
+	if h.Class != "" {
+		write(KeyClass, h.Class)
+	}
+	if h.Description != "" {
+		write(KeyDescription, h.Description)
+	}
+	for _, r := range h.Rules {
+		write(KeyRule, r)
+	}
+	for _, c := range h.Crons {
+		write(KeyCron, c)
+	}
+	write(KeyAdmin, strconv.FormatBool(h.Admin))
+	if h.Disable {
+		write(KeyDisable, "true")
+	}
+	if h.Priority != 0 {
+		write(KeyPriority, strconv.Itoa(h.Priority))
+	}
+	if len(h.ImTypes) > 0 {
+		write(KeyImType, strings.Join(h.ImTypes, ","))
+	}
+	if h.Public {
+		write(KeyPublic, "true")
+	}
+	for _, f := range h.Extra {
+		write(f.Key, f.Value)
+	}
+	return sb.String()
+}
+
+func (h *Header) String() string {
+	return h.Format()
+}
+
+/**
+ * @description: 头注校验失败的全部问题
+ */
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "头注校验失败: " + strings.Join(e.Problems, "; ")
+}
+
+/**
+ * @description: 校验头注：名称不能为空，规则必须是合法的正则，cron必须合法，平台必须是已知平台
+ * @return {error} 存在问题时为*ValidationError
+ */
+func (h *Header) Validate() error {
+	var problems []string
+	if strings.TrimSpace(h.Title) == "" {
+		problems = append(problems, "缺少title")
+	}
+	if len(h.Rules) == 0 && len(h.Crons) == 0 {
+		problems = append(problems, "至少需要一条rule或cron")
+	}
+	for _, r := range h.Rules {
+		if _, err := middleware.CompileRule(r); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	for _, c := range h.Crons {
+		if _, err := middleware.ParseCron(c); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	for _, t := range h.ImTypes {
+		if _, ok := middleware.Platforms[t]; !ok {
+			problems = append(problems, "未知的平台"+t)
+		}
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+/**
+ * @description: 离线测试消息能否触发插件，与autMan一样按规则顺序匹配，规则按middleware.CompileRule编译
+ * @param {string} msg 消息内容
+ * @return {string} 匹配到的规则
+ * @return {*middleware.Captures} 参数，与运行时Params的结果一致
+ * @return {bool} 是否匹配
+ */
+func (h *Header) Match(msg string) (string, *middleware.Captures, bool) {
+	for _, r := range h.Rules {
+		re, err := middleware.CompileRule(r)
+		if err != nil {
+			continue
+		}
+		m := re.FindStringSubmatch(msg)
+		if m == nil {
+			continue
+		}
+		return r, &middleware.Captures{Values: m[1:], Names: re.SubexpNames()[1:]}, true
+	}
+	return "", nil, false
+}
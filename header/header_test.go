@@ -0,0 +1,144 @@
+package header
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/hdbjlizhe/middleware"
+)
+
+const sample = `#!/usr/bin/env autman
+//[title: 天气]
+//[version: 1.2.0]
+//[author: someone]
+//[rule: 天气 ?]
+//[rule: raw ^禁言 (?P<who>\S+) (?P<time>\d+[smh])$]
+//[cron: 0 8 * * *]
+//[admin: true]
+//[priority: 3]
+//[imType: qq,wx，tg]
+//[icon: weather.png]
+package main
+
+//[rule: 不在头注中]
+`
+
+func TestParse(t *testing.T) {
+	h, err := Parse(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Header{
+		Title:    "天气",
+		Version:  "1.2.0",
+		Author:   "someone",
+		Rules:    []string{"天气 ?", `raw ^禁言 (?P<who>\S+) (?P<time>\d+[smh])$`},
+		Crons:    []string{"0 8 * * *"},
+		Admin:    true,
+		Priority: 3,
+		ImTypes:  []string{"qq", "wx", "tg"},
+		Extra:    []Field{{Key: "icon", Value: "weather.png"}},
+	}
+	if !reflect.DeepEqual(h, want) {
+		t.Errorf("Parse = %+v, want %+v", h, want)
+	}
+	if err := h.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+}
+
+func TestParseInvalidValue(t *testing.T) {
+	for _, src := range []string{"//[admin: maybe]", "//[priority: high]"} {
+		if _, err := Parse(src); err == nil {
+			t.Errorf("Parse(%q) expected error", src)
+		}
+	}
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	h, err := Parse(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, prefix := range []string{"//", "#"} {
+		again, err := Parse(h.Format(prefix))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(again, h) {
+			t.Errorf("prefix %q: Parse(Format()) = %+v, want %+v", prefix, again, h)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	h := &Header{Rules: []string{"raw (x"}, Crons: []string{"61 * * * *"}, ImTypes: []string{"zz"}}
+	err := h.Validate()
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Validate = %v, want *ValidationError", err)
+	}
+	if len(verr.Problems) != 4 {
+		t.Errorf("Problems = %q, want 4 problems", verr.Problems)
+	}
+	if err := (&Header{Title: "x"}).Validate(); err == nil {
+		t.Error("header without rule or cron should be invalid")
+	}
+}
+
+func TestCompileRule(t *testing.T) {
+	tests := []struct {
+		rule  string
+		msg   string
+		match bool
+		want  []string
+	}{
+		{"天气 ?", "天气 北京", true, []string{"北京"}},
+		{"天气 ?", "天气 北京 朝阳", true, []string{"北京 朝阳"}},
+		{"天气 ?", "今天天气 北京", false, nil},
+		{"天气 ?", "天气", false, nil},
+		{"? 加 ?", "1 加 2", true, []string{"1", "2"}},
+		{"价格(元) ?", "价格(元) 5", true, []string{"5"}},
+		{"a.b", "axb", false, nil},
+		{`^查询 (\d+)$`, "查询 42", true, []string{"42"}},
+		{`^查询 (\d+)$`, "查询 abc", false, nil},
+		{`raw 查询 (\d+)`, "请查询 42 号", true, []string{"42"}},
+		{`raw ^a?b$`, "b", true, []string{}},
+	}
+	for _, tt := range tests {
+		re, err := middleware.CompileRule(tt.rule)
+		if err != nil {
+			t.Errorf("middleware.CompileRule(%q): %v", tt.rule, err)
+			continue
+		}
+		m := re.FindStringSubmatch(tt.msg)
+		if (m != nil) != tt.match {
+			t.Errorf("rule %q on %q: match = %v, want %v", tt.rule, tt.msg, m != nil, tt.match)
+			continue
+		}
+		if m != nil && !reflect.DeepEqual(m[1:], tt.want) {
+			t.Errorf("rule %q on %q: captures = %q, want %q", tt.rule, tt.msg, m[1:], tt.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	h, err := Parse(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rule, c, ok := h.Match("禁言 @123 10m")
+	if !ok || rule != h.Rules[1] {
+		t.Fatalf("Match = %q, %v, want second rule", rule, ok)
+	}
+	if who, err := c.User("who"); err != nil || who != "123" {
+		t.Errorf("who = %q, %v", who, err)
+	}
+	if d, err := c.Duration("time"); err != nil || d.Minutes() != 10 {
+		t.Errorf("time = %v, %v", d, err)
+	}
+	if _, _, ok := h.Match("不在头注中"); ok {
+		t.Error("rules after the header must be ignored")
+	}
+}
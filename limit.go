@@ -0,0 +1,232 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 限流状态默认保存的数据桶
+const limitBucket = "ratelimit"
+
+/**
+ * @description: 默认的限流提示，{wait}会替换为剩余等待时间
+ */
+var DefaultCooldownMessage = "操作太频繁，请{wait}后再试"
+
+var ErrLimiterConfig = errors.New("限流器配置无效：Capacity与Refill、Limit与Window必须大于0")
+
+// 串行化限流状态的读写。只在同一进程内有效，多个插件进程同时读写同一规则时仍可能超出限制
+var limitMu sync.Mutex
+
+/**
+ * @description: 限流器，状态保存在数据桶中，插件重启后仍然有效。
+ * 读写状态只在同一进程内串行化，同一规则被多个插件进程并发触发时，放行次数可能略超限制
+ */
+type Limiter interface {
+	// 消耗key的一次额度，返回是否允许，以及不允许时需要等待的时间
+	Allow(key string) (bool, time.Duration, error)
+}
+
+/**
+ * @description: 令牌桶，最多积攒Capacity次额度，每隔Refill恢复一次
+ */
+type TokenBucket struct {
+	Name     string        // 限流规则名称，不同规则互不影响
+	Capacity int           // 令牌桶容量
+	Refill   time.Duration // 恢复一个令牌所需时间
+	Bucket   string        // 保存状态的数据桶，默认"ratelimit"
+}
+
+func (l *TokenBucket) Allow(key string) (bool, time.Duration, error) {
+	if l.Capacity <= 0 || l.Refill <= 0 {
+		return false, 0, ErrLimiterConfig
+	}
+	limitMu.Lock()
+	defer limitMu.Unlock()
+	var state struct {
+		Tokens float64 `json:"tokens"`
+		Last   int64   `json:"last"`
+	}
+	now := time.Now()
+	k := "token:" + l.Name + ":" + key
+	if !loadLimitState(l.Bucket, k, &state) {
+		state.Tokens = float64(l.Capacity)
+		state.Last = now.UnixMilli()
+	}
+	elapsed := now.Sub(time.UnixMilli(state.Last))
+	state.Tokens += float64(elapsed) / float64(l.Refill)
+	if state.Tokens > float64(l.Capacity) {
+		state.Tokens = float64(l.Capacity)
+	}
+	state.Last = now.UnixMilli()
+	if state.Tokens < 1 {
+		wait := time.Duration((1 - state.Tokens) * float64(l.Refill))
+		return false, wait, saveLimitState(l.Bucket, k, state)
+	}
+	state.Tokens--
+	return true, 0, saveLimitState(l.Bucket, k, state)
+}
+
+/**
+ * @description: 固定窗口，每个Window时间段内最多Limit次，如每小时5次
+ */
+type FixedWindow struct {
+	Name   string        // 限流规则名称，不同规则互不影响
+	Limit  int           // 窗口内最大次数
+	Window time.Duration // 窗口长度
+	Bucket string        // 保存状态的数据桶，默认"ratelimit"
+}
+
+func (l *FixedWindow) Allow(key string) (bool, time.Duration, error) {
+	if l.Limit <= 0 || l.Window <= 0 {
+		return false, 0, ErrLimiterConfig
+	}
+	limitMu.Lock()
+	defer limitMu.Unlock()
+	var state struct {
+		Start int64 `json:"start"`
+		Count int   `json:"count"`
+	}
+	now := time.Now()
+	k := "fixed:" + l.Name + ":" + key
+	start := now.Truncate(l.Window).UnixMilli()
+	if !loadLimitState(l.Bucket, k, &state) || state.Start != start {
+		state.Start, state.Count = start, 0
+	}
+	if state.Count >= l.Limit {
+		return false, time.UnixMilli(start).Add(l.Window).Sub(now), nil
+	}
+	state.Count++
+	return true, 0, saveLimitState(l.Bucket, k, state)
+}
+
+/**
+ * @description: 滑动窗口，任意Window时间段内最多Limit次
+ */
+type SlidingWindow struct {
+	Name   string        // 限流规则名称，不同规则互不影响
+	Limit  int           // 窗口内最大次数
+	Window time.Duration // 窗口长度
+	Bucket string        // 保存状态的数据桶，默认"ratelimit"
+}
+
+func (l *SlidingWindow) Allow(key string) (bool, time.Duration, error) {
+	if l.Limit <= 0 || l.Window <= 0 {
+		return false, 0, ErrLimiterConfig
+	}
+	limitMu.Lock()
+	defer limitMu.Unlock()
+	var hits []int64
+	now := time.Now()
+	k := "sliding:" + l.Name + ":" + key
+	loadLimitState(l.Bucket, k, &hits)
+	since := now.Add(-l.Window).UnixMilli()
+	kept := hits[:0]
+	for _, hit := range hits {
+		if hit > since {
+			kept = append(kept, hit)
+		}
+	}
+	if len(kept) >= l.Limit {
+		return false, time.UnixMilli(kept[len(kept)-l.Limit]).Add(l.Window).Sub(now), saveLimitState(l.Bucket, k, kept)
+	}
+	return true, 0, saveLimitState(l.Bucket, k, append(kept, now.UnixMilli()))
+}
+
+func loadLimitState(bucket, key string, v interface{}) bool {
+	if bucket == "" {
+		bucket = limitBucket
+	}
+	data := BucketGet(bucket, key)
+	return data != "" && json.Unmarshal([]byte(data), v) == nil
+}
+
+func saveLimitState(bucket, key string, v interface{}) error {
+	if bucket == "" {
+		bucket = limitBucket
+	}
+	data, _ := json.Marshal(v)
+	return BucketSet(bucket, key, string(data))
+}
+
+/**
+ * @description: 按用户限流的键
+ * @return {string}
+ */
+func UserKey(s *Sender) string {
+	return "user:" + s.GetImtype() + ":" + s.GetUserID()
+}
+
+/**
+ * @description: 按群组限流的键
+ * @return {string}
+ */
+func ChatKey(s *Sender) string {
+	return "chat:" + s.GetImtype() + ":" + s.GetChatID()
+}
+
+/**
+ * @description: 按插件限流的键
+ * @return {string}
+ */
+func PluginKey(s *Sender) string {
+	return "plugin:" + s.GetPluginName()
+}
+
+/**
+ * @description: 组合多个限流键，如JoinKeys(PluginKey(s), UserKey(s))表示每个用户在本插件内单独限流
+ * @return {string}
+ */
+func JoinKeys(keys ...string) string {
+	return strings.Join(keys, "|")
+}
+
+/**
+ * @description: 检查限流，超出限制时回复冷却提示
+ * @param {Limiter} l 限流器
+ * @param {string} key 限流键
+ * @param {string} message 冷却提示，{wait}会替换为剩余等待时间，为空时使用DefaultCooldownMessage
+ * @return {bool} 是否允许继续执行，限流器配置无效时不回复并返回false
+ */
+func (s *Sender) RateLimit(l Limiter, key, message string) bool {
+	ok, wait, err := l.Allow(key)
+	if ok {
+		return true
+	}
+	if errors.Is(err, ErrLimiterConfig) {
+		return false
+	}
+	if message == "" {
+		message = DefaultCooldownMessage
+	}
+	s.Reply(strings.ReplaceAll(message, "{wait}", FormatWait(wait)))
+	return false
+}
+
+/**
+ * @description: 将等待时间格式化为"1小时5分"、"30秒"
+ * @param {time.Duration} d 等待时间
+ * @return {string}
+ */
+func FormatWait(d time.Duration) string {
+	secs := int64((d + time.Second - 1) / time.Second)
+	if secs < 1 {
+		secs = 1
+	}
+	h, m, sec := secs/3600, secs%3600/60, secs%60
+	switch {
+	case h > 0 && m > 0:
+		return fmt.Sprintf("%d小时%d分", h, m)
+	case h > 0:
+		return fmt.Sprintf("%d小时", h)
+	case m > 0 && sec > 0:
+		return fmt.Sprintf("%d分%d秒", m, sec)
+	case m > 0:
+		return fmt.Sprintf("%d分钟", m)
+	}
+	return fmt.Sprintf("%d秒", sec)
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/buger/jsonparser"
+)
+
+var ErrListenTimeout = fmt.Errorf("监听消息超时: %w", ErrTimeout)
+
+// 客户端超时相对服务端等待超时的余量，避免服务端的超时响应与客户端超时竞争
+const listenPadding = 5 * time.Second
+
+/**
+ * @description: 监听选项
+ */
+type ListenOptions struct {
+	Timeout time.Duration // 服务端等待超时，默认60秒
+	Group   bool          // 监听当前群组内所有用户的消息，而不仅是当前用户
+	UserID  string        // 监听当前群组内指定用户的消息
+}
+
+/**
+ * @description: 监听到的消息
+ */
+type ListenResult struct {
+	Text        string    `json:"text"`
+	MessageID   string    `json:"messageid"`
+	Imtype      string    `json:"imtype"`
+	UserID      string    `json:"userid"`
+	ChatID      string    `json:"chatid"`
+	Attachments []Segment `json:"-"` // 消息中的图片、语音、视频等非文本片段
+}
+
+/**
+ * @description: 等待用户输入，超时返回ErrListenTimeout，ctx取消时返回ctx.Err()
+ * 与Listen不同，空消息或仅含图片的消息不会被当作超时
+ * @param {context.Context} ctx 上下文
+ * @param {ListenOptions} opts 监听选项
+ * @return {*ListenResult} 用户输入的消息及其元数据
+ */
+func (s *Sender) ListenMessage(ctx context.Context, opts ListenOptions) (*ListenResult, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = time.Minute
+	}
+	params := map[string]interface{}{
+		"senderid": s.SenderID,
+		"timeout":  opts.Timeout.Milliseconds(),
+		"detail":   true,
+		"group":    opts.Group,
+		"userid":   opts.UserID,
+	}
+	body, _ := json.Marshal(params)
+	req, err := http.NewRequestWithContext(ctx, "POST", sockUrl()+"/listen", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := &http.Client{Transport: transport, Timeout: opts.Timeout + listenPadding}
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return parseListenResult(data)
+}
+
+func parseListenResult(resp []byte) (*ListenResult, error) {
+	if timeout, _ := jsonparser.GetBoolean(resp, "timeout"); timeout {
+		return nil, ErrListenTimeout
+	}
+	value, typ, _, err := jsonparser.Get(resp, "data")
+	if err != nil || typ == jsonparser.NotExist || typ == jsonparser.Null {
+		return nil, ErrListenTimeout
+	}
+	result := &ListenResult{}
+	switch typ {
+	case jsonparser.Object:
+		if err := json.Unmarshal(value, result); err != nil {
+			return nil, err
+		}
+	case jsonparser.String:
+		// 旧版本服务端只返回消息文本，超时与空消息无法区分，按超时处理
+		text, _ := jsonparser.GetString(resp, "data")
+		if text == "" {
+			return nil, ErrListenTimeout
+		}
+		result.Text = text
+	default:
+		return nil, fmt.Errorf("无法解析的监听结果: %s", value)
+	}
+	for _, seg := range ParseMessage(result.Text).Segments {
+		if seg.Type != "text" {
+			result.Attachments = append(result.Attachments, seg)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,234 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 媒体类型
+const (
+	MediaImage    = "image"
+	MediaVoice    = "voice"
+	MediaVideo    = "video"
+	MediaDocument = "file"
+)
+
+var (
+	ErrMediaTooLarge    = errors.New("媒体文件超出大小限制")
+	ErrMediaType        = errors.New("媒体文件类型不匹配")
+	ErrMediaUnsupported = errors.New("当前平台不支持该媒体类型")
+	ErrMediaEmpty       = errors.New("媒体来源为空")
+)
+
+/**
+ * @description: 各媒体类型的大小上限，单位：字节
+ */
+var MediaSizeLimits = map[string]int64{
+	MediaImage:    10 << 20,
+	MediaVoice:    10 << 20,
+	MediaVideo:    50 << 20,
+	MediaDocument: 100 << 20,
+}
+
+/**
+ * @description: 媒体来源，链接、本地文件、字节和io.Reader四选一
+ */
+type Media struct {
+	URL    string
+	Path   string
+	Data   []byte
+	Reader io.Reader
+	Name   string // 文件名，用于根据扩展名识别MIME类型
+}
+
+/**
+ * @description: 链接媒体
+ * @param {string} url 媒体链接
+ */
+func MediaURL(url string) Media {
+	return Media{URL: url}
+}
+
+/**
+ * @description: 本地文件媒体
+ * @param {string} path 文件路径
+ */
+func MediaFile(path string) Media {
+	return Media{Path: path, Name: filepath.Base(path)}
+}
+
+/**
+ * @description: 字节媒体
+ * @param {string} name 文件名，可为空
+ * @param {[]byte} data 文件内容
+ */
+func MediaBytes(name string, data []byte) Media {
+	return Media{Data: data, Name: name}
+}
+
+/**
+ * @description: io.Reader媒体
+ * @param {string} name 文件名，可为空
+ * @param {io.Reader} r 文件内容
+ */
+func MediaReader(name string, r io.Reader) Media {
+	return Media{Reader: r, Name: name}
+}
+
+/**
+ * @description: 读取媒体内容，超出limit时返回ErrMediaTooLarge，limit为0时不限制
+ * @param {int64} limit 大小上限
+ * @return {[]byte} 内容
+ * @return {string} MIME类型
+ */
+func (m Media) Load(limit int64) ([]byte, string, error) {
+	var r io.Reader
+	switch {
+	case m.Data != nil:
+		r = bytes.NewReader(m.Data)
+	case m.Reader != nil:
+		r = m.Reader
+	case m.Path != "":
+		f, err := os.Open(m.Path)
+		if err != nil {
+			return nil, "", err
+		}
+		defer f.Close()
+		r = f
+	default:
+		return nil, "", ErrMediaEmpty
+	}
+	if limit > 0 {
+		r = io.LimitReader(r, limit+1)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, "", err
+	}
+	if limit > 0 && int64(len(data)) > limit {
+		return nil, "", fmt.Errorf("%w: %d字节", ErrMediaTooLarge, limit)
+	}
+	return data, m.detectMime(data), nil
+}
+
+func (m Media) detectMime(data []byte) string {
+	typ := http.DetectContentType(data)
+	if typ == "application/octet-stream" || strings.HasPrefix(typ, "text/plain") {
+		if byExt := mime.TypeByExtension(filepath.Ext(m.Name)); byExt != "" {
+			typ = byExt
+		}
+	}
+	if i := strings.Index(typ, ";"); i >= 0 {
+		typ = typ[:i]
+	}
+	return typ
+}
+
+/**
+ * @description: 生成发送用的媒体地址，链接原样返回，其他来源校验后编码为base64://
+ * @param {string} kind 媒体类型：image/voice/video/file
+ * @return {string}
+ */
+func (m Media) Payload(kind string) (string, error) {
+	if m.URL != "" {
+		return m.URL, nil
+	}
+	data, typ, err := m.Load(MediaSizeLimits[kind])
+	if err != nil {
+		return "", err
+	}
+	if !mimeMatches(kind, typ) {
+		return "", fmt.Errorf("%w: 需要%s，实际为%s", ErrMediaType, kind, typ)
+	}
+	return "base64://" + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// 校验MIME类型与媒体类型是否相符，无法识别的类型交由平台判断
+func mimeMatches(kind, typ string) bool {
+	if typ == "application/octet-stream" {
+		return true
+	}
+	switch kind {
+	case MediaImage:
+		return strings.HasPrefix(typ, "image/")
+	case MediaVoice:
+		return strings.HasPrefix(typ, "audio/") || typ == "application/ogg"
+	case MediaVideo:
+		return strings.HasPrefix(typ, "video/")
+	}
+	return true
+}
+
+/**
+ * @description: 平台是否支持发送指定类型的媒体
+ * @param {string} kind 媒体类型：image/voice/video/file
+ * @return {bool}
+ */
+func (p Platform) Supports(kind string) bool {
+	switch kind {
+	case MediaImage:
+		return p.Image
+	case MediaVoice:
+		return p.Voice
+	case MediaVideo:
+		return p.Video
+	case MediaDocument:
+		return p.File
+	}
+	return false
+}
+
+/**
+ * @description: 回复图片，支持链接、本地文件、字节和io.Reader
+ * @param {Media} src 媒体来源
+ * @return {[]string} 消息ID
+ */
+func (s *Sender) ReplyImageFrom(src Media) ([]string, error) {
+	payload, err := s.mediaPayload(MediaImage, src)
+	if err != nil {
+		return nil, err
+	}
+	return s.ReplyImage(payload)
+}
+
+/**
+ * @description: 回复语音，支持链接、本地文件、字节和io.Reader
+ * @param {Media} src 媒体来源
+ * @return {[]string} 消息ID
+ */
+func (s *Sender) ReplyVoiceFrom(src Media) ([]string, error) {
+	payload, err := s.mediaPayload(MediaVoice, src)
+	if err != nil {
+		return nil, err
+	}
+	return s.ReplyVoice(payload)
+}
+
+/**
+ * @description: 回复视频，支持链接、本地文件、字节和io.Reader
+ * @param {Media} src 媒体来源
+ * @return {[]string} 消息ID
+ */
+func (s *Sender) ReplyVideoFrom(src Media) ([]string, error) {
+	payload, err := s.mediaPayload(MediaVideo, src)
+	if err != nil {
+		return nil, err
+	}
+	return s.ReplyVideo(payload)
+}
+
+func (s *Sender) mediaPayload(kind string, src Media) (string, error) {
+	imtype := s.GetImtype()
+	if p, ok := Platforms[imtype]; ok && !p.Supports(kind) {
+		return "", fmt.Errorf("%w: %s不支持%s", ErrMediaUnsupported, imtype, kind)
+	}
+	return src.Payload(kind)
+}
@@ -0,0 +1,235 @@
+package middleware
+
+import (
+	"sort"
+	"strings"
+)
+
+/**
+ * @description: 消息片段，对应一个CQ码或一段纯文本
+ * Type为"text"时，文本内容存放在Data["text"]中
+ */
+type Segment struct {
+	Type string
+	Data map[string]string
+}
+
+/**
+ * @description: 消息构造器，用于组合文本与CQ码，渲染时自动转义
+ */
+type Message struct {
+	Segments []Segment
+}
+
+/**
+ * @description: 创建消息构造器
+ */
+func NewMessage() *Message {
+	return &Message{}
+}
+
+/**
+ * @description: 追加一个消息片段
+ * @param {string} typ CQ码类型
+ * @param {map[string]string} data CQ码参数
+ */
+func (m *Message) Append(typ string, data map[string]string) *Message {
+	m.Segments = append(m.Segments, Segment{Type: typ, Data: data})
+	return m
+}
+
+/**
+ * @description: 追加纯文本
+ * @param {string} text 文本内容
+ */
+func (m *Message) Text(text string) *Message {
+	if text == "" {
+		return m
+	}
+	return m.Append("text", map[string]string{"text": text})
+}
+
+/**
+ * @description: @指定用户
+ * @param {string} userid 用户ID
+ */
+func (m *Message) At(userid string) *Message {
+	return m.Append("at", map[string]string{"qq": userid})
+}
+
+/**
+ * @description: @全体成员
+ */
+func (m *Message) AtAll() *Message {
+	return m.Append("at", map[string]string{"qq": "all"})
+}
+
+/**
+ * @description: 图片
+ * @param {string} file 图片链接或文件
+ */
+func (m *Message) Image(file string) *Message {
+	return m.Append("image", map[string]string{"file": file})
+}
+
+/**
+ * @description: 表情
+ * @param {string} id 表情ID
+ */
+func (m *Message) Face(id string) *Message {
+	return m.Append("face", map[string]string{"id": id})
+}
+
+/**
+ * @description: 引用回复
+ * @param {string} messageid 被回复的消息ID
+ */
+func (m *Message) Reply(messageid string) *Message {
+	return m.Append("reply", map[string]string{"id": messageid})
+}
+
+/**
+ * @description: 语音
+ * @param {string} file 语音链接或文件
+ */
+func (m *Message) Record(file string) *Message {
+	return m.Append("record", map[string]string{"file": file})
+}
+
+/**
+ * @description: 视频
+ * @param {string} file 视频链接或文件
+ */
+func (m *Message) Video(file string) *Message {
+	return m.Append("video", map[string]string{"file": file})
+}
+
+/**
+ * @description: 渲染为CQ码字符串，参数按名称排序
+ * @return {string}
+ */
+func (m *Message) String() string {
+	var sb strings.Builder
+	for _, seg := range m.Segments {
+		sb.WriteString(seg.String())
+	}
+	return sb.String()
+}
+
+/**
+ * @description: 拼接消息中的纯文本片段
+ * @return {string}
+ */
+func (m *Message) PlainText() string {
+	var sb strings.Builder
+	for _, seg := range m.Segments {
+		if seg.Type == "text" {
+			sb.WriteString(seg.Data["text"])
+		}
+	}
+	return sb.String()
+}
+
+/**
+ * @description: 渲染单个片段
+ * @return {string}
+ */
+func (seg Segment) String() string {
+	if seg.Type == "text" {
+		return EscapeCQ(seg.Data["text"], false)
+	}
+	keys := make([]string, 0, len(seg.Data))
+	for k := range seg.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	sb.WriteString("[CQ:")
+	sb.WriteString(seg.Type)
+	for _, k := range keys {
+		sb.WriteString(",")
+		sb.WriteString(k)
+		sb.WriteString("=")
+		sb.WriteString(EscapeCQ(seg.Data[k], true))
+	}
+	sb.WriteString("]")
+	return sb.String()
+}
+
+/**
+ * @description: CQ码转义
+ * @param {string} s 原始字符串
+ * @param {bool} param 是否为CQ码参数，参数中还需转义逗号
+ * @return {string}
+ */
+func EscapeCQ(s string, param bool) string {
+	s = strings.ReplaceAll(s, "&", "&amp;")
+	s = strings.ReplaceAll(s, "[", "&#91;")
+	s = strings.ReplaceAll(s, "]", "&#93;")
+	if param {
+		s = strings.ReplaceAll(s, ",", "&#44;")
+	}
+	return s
+}
+
+/**
+ * @description: CQ码反转义
+ * @param {string} s 转义后的字符串
+ * @return {string}
+ */
+func UnescapeCQ(s string) string {
+	s = strings.ReplaceAll(s, "&#44;", ",")
+	s = strings.ReplaceAll(s, "&#91;", "[")
+	s = strings.ReplaceAll(s, "&#93;", "]")
+	return strings.ReplaceAll(s, "&amp;", "&")
+}
+
+/**
+ * @description: 将带CQ码的文本解析为消息片段，无法识别的"["按纯文本处理
+ * @param {string} text 带CQ码的文本
+ * @return {*Message}
+ */
+func ParseMessage(text string) *Message {
+	m := NewMessage()
+	for text != "" {
+		start := strings.Index(text, "[CQ:")
+		if start < 0 {
+			m.Text(UnescapeCQ(text))
+			break
+		}
+		end := strings.Index(text[start:], "]")
+		if end < 0 {
+			m.Text(UnescapeCQ(text))
+			break
+		}
+		m.Text(UnescapeCQ(text[:start]))
+		code := text[start+4 : start+end]
+		text = text[start+end+1:]
+		parts := strings.Split(code, ",")
+		data := map[string]string{}
+		for _, part := range parts[1:] {
+			if k, v, ok := strings.Cut(part, "="); ok {
+				data[k] = UnescapeCQ(v)
+			}
+		}
+		m.Append(parts[0], data)
+	}
+	return m
+}
+
+/**
+ * @description: 获取消息内容并解析为消息片段
+ * @return {*Message}
+ */
+func (s *Sender) GetMessageSegments() *Message {
+	return ParseMessage(s.GetMessage())
+}
+
+/**
+ * @description: 回复消息构造器生成的内容
+ * @param {*Message} m 消息
+ * @return {[]string} 消息ID
+ */
+func (s *Sender) ReplyMessage(m *Message) ([]string, error) {
+	return s.Reply(m.String())
+}
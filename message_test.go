@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEscapeCQ(t *testing.T) {
+	tests := []struct {
+		in    string
+		param bool
+		want  string
+	}{
+		{"a[b]c", false, "a&#91;b&#93;c"},
+		{"a,b", false, "a,b"},
+		{"a,b", true, "a&#44;b"},
+		{"&", false, "&amp;"},
+		{"&#91;", false, "&amp;#91;"},
+		{"[x,y]&", true, "&#91;x&#44;y&#93;&amp;"},
+	}
+	for _, tt := range tests {
+		if got := EscapeCQ(tt.in, tt.param); got != tt.want {
+			t.Errorf("EscapeCQ(%q, %v) = %q, want %q", tt.in, tt.param, got, tt.want)
+		}
+		if got := UnescapeCQ(EscapeCQ(tt.in, tt.param)); got != tt.in {
+			t.Errorf("UnescapeCQ(EscapeCQ(%q, %v)) = %q", tt.in, tt.param, got)
+		}
+	}
+}
+
+func TestParseMessageRoundTrip(t *testing.T) {
+	tests := []*Message{
+		NewMessage().Text("plain text"),
+		NewMessage().Text("[not a code], & [CQ:face,id=1]"),
+		NewMessage().Text("literal &#91; &#44; &amp;"),
+		NewMessage().At("123").Text(" hi"),
+		NewMessage().Reply("9").Text("a,b").Image("http://x/a.jpg?w=1,h=2&q=[3]"),
+		NewMessage().Append("share", map[string]string{"title": "a]b,c&d", "url": "u"}),
+	}
+	for _, m := range tests {
+		text := m.String()
+		got := ParseMessage(text)
+		if !reflect.DeepEqual(got.Segments, m.Segments) {
+			t.Errorf("ParseMessage(%q) = %+v, want %+v", text, got.Segments, m.Segments)
+		}
+		if again := got.String(); again != text {
+			t.Errorf("round trip of %q produced %q", text, again)
+		}
+	}
+}
+
+func TestParseMessageUnknownBracket(t *testing.T) {
+	got := ParseMessage("a [CQ:face,id=1")
+	want := []Segment{{Type: "text", Data: map[string]string{"text": "a [CQ:face,id=1"}}}
+	if !reflect.DeepEqual(got.Segments, want) {
+		t.Errorf("ParseMessage = %+v, want %+v", got.Segments, want)
+	}
+}
@@ -52,6 +52,16 @@ func SetPort() {
  * @param {func(string)} func 消息监听句柄，回调函数
  */
 func AddMsgListener(imtype, chatid, userid string, exitChannel chan struct{}, function func(string)) {
+	msghook(imtype, chatid, userid, exitChannel, func(event, data string) {
+		// 只转发聊天消息，回调、请求、通知等事件请使用AddEventListener
+		if event == "" || event == EventMessage {
+			function(strings.ReplaceAll(data, "\\n", "\n"))
+		}
+	})
+}
+
+// 连接msghook并按SSE格式读取，event为服务端在data之前通过event行声明的事件类型，未声明时为空
+func msghook(imtype, chatid, userid string, exitChannel chan struct{}, handle func(event, data string)) {
 	//创建ess连接
 	url := fmt.Sprintf("%s/msghook", httpUrl())
 
@@ -79,27 +89,24 @@ func AddMsgListener(imtype, chatid, userid string, exitChannel chan struct{}, fu
 	reader := bufio.NewReader(resp.Body)
 
 	go func() {
+		event := ""
 		for {
 			// 按行读取数据
 			data, err := reader.ReadBytes('\n')
 			if err != nil {
 				fmt.Printf("Read error: %v\n", err)
 				break
-			} else {
-				fmt.Printf("Read: %s\n", data)
 			}
-			// 这里简单地打印出来，实际应用中可能需要根据行的内容进行解析
-			msg := strings.TrimSuffix(string(data), "\n")
-			fmt.Printf("Received: %s", msg)
-			if strings.Contains(msg, "event:message") {
-				continue
-			} else {
-				msg = strings.TrimLeft(msg, "data:")
+			line := strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r")
+			switch {
+			case line == "":
+				// 空行表示一个事件结束
+				event = ""
+			case strings.HasPrefix(line, "event:"):
+				event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+			case strings.HasPrefix(line, "data:"):
+				handle(event, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
 			}
-
-			// 处理消息
-			msg = strings.ReplaceAll(msg, "\\n", "\n")
-			function(msg)
 		}
 	}()
 
@@ -584,7 +591,8 @@ func (s *Sender) BreakIn(content string) error {
 }
 
 /*
-* @description: 获取用户触发的关键词，对应头注中rule规则中的小括号或问号
+* @description: 获取用户触发的关键词，对应头注中rule规则中的小括号或问号，参数不存在时返回空字符串
+* 需要多个参数时使用Params一次获取，需要类型转换时使用ParamInt、ParamDuration等
 * @param {int} index 参数索引
 * @return {string} 参数值
  */
@@ -714,7 +722,7 @@ func (s *Sender) Listen(timeout int) string {
 		"timeout":  timeout,
 	}
 	body, _ := json.Marshal(params)
-	resp, _ := httplib.Post(sockUrl()+"/listen").Header("Content-Type", "application/json").Body(body).SetTransport(transport).SetTimeout(time.Millisecond*time.Duration(timeout), time.Millisecond*time.Duration(timeout)).Bytes()
+	resp, _ := httplib.Post(sockUrl()+"/listen").Header("Content-Type", "application/json").Body(body).SetTransport(transport).SetTimeout(time.Millisecond*time.Duration(timeout)+listenPadding, time.Millisecond*time.Duration(timeout)+listenPadding).Bytes()
 	rlt, _ := jsonparser.GetString(resp, "data")
 	return rlt
 }
@@ -722,7 +730,7 @@ func (s *Sender) Listen(timeout int) string {
 /*
 * @description: 等待用户支付
 * @param {string} timeout 超时，单位：毫秒
-* @return {string} 用户支付信息json字符串
+* @return {string} 用户支付信息json字符串，可使用WaitPayment获取解析后的支付结果
  */
 func (s *Sender) WaitPay(exitCode string, timeout int) string {
 	params := map[string]interface{}{
@@ -803,6 +811,9 @@ func (s *Sender) GroupUnban(userid string) error {
 	}
 }
 
+// @description: 开启当前群的全员禁言
+//
+// Deprecated: 参数userid没有实际意义，请使用GroupMute指定群组和禁言时长
 func (s *Sender) GroupWholeBan(userid string) error {
 	params := map[string]interface{}{
 		"senderid": s.SenderID,
@@ -816,6 +827,9 @@ func (s *Sender) GroupWholeBan(userid string) error {
 	}
 }
 
+// @description: 解除当前群的全员禁言
+//
+// Deprecated: 参数userid没有实际意义，请使用GroupUnmute指定群组
 func (s *Sender) GroupWholeUnban(userid string) error {
 	params := map[string]interface{}{
 		"senderid": s.SenderID,
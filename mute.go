@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/v2/client/httplib"
+)
+
+// 保存全员禁言状态的数据桶
+const muteBucket = "group_mute"
+
+var muteTimers = struct {
+	sync.Mutex
+	items map[string]*time.Timer
+}{items: map[string]*time.Timer{}}
+
+/**
+ * @description: 全员禁言状态
+ */
+type MuteState struct {
+	Imtype string `json:"imtype"`
+	ChatID string `json:"chatid"`
+	Muted  bool   `json:"muted"`
+	Until  int64  `json:"until"` // 自动解除时间，unix时间戳，单位：秒，为0时不自动解除
+}
+
+/**
+ * @description: 开启指定群的全员禁言，duration大于0时到期自动解除
+ * 自动解除由当前进程定时执行，插件重启后调用ResumeGroupMutes恢复
+ * @param {string} imType 平台类型
+ * @param {string} chatID 群组ID
+ * @param {time.Duration} duration 禁言时长，为0时不自动解除
+ * @return {*MuteState} 禁言后的状态
+ */
+func GroupMute(imType, chatID string, duration time.Duration) (*MuteState, error) {
+	if err := groupWholeBan("/groupWholeBan", imType, chatID); err != nil {
+		return nil, err
+	}
+	state := &MuteState{Imtype: imType, ChatID: chatID, Muted: true}
+	if duration > 0 {
+		state.Until = time.Now().Add(duration).Unix()
+	}
+	if err := saveMuteState(state); err != nil {
+		return state, err
+	}
+	scheduleUnmute(state)
+	return state, nil
+}
+
+/**
+ * @description: 解除指定群的全员禁言，并取消自动解除计划
+ * @param {string} imType 平台类型
+ * @param {string} chatID 群组ID
+ * @return {*MuteState} 解除后的状态
+ */
+func GroupUnmute(imType, chatID string) (*MuteState, error) {
+	stopUnmute(imType + ":" + chatID)
+	if err := groupWholeBan("/groupWholeUnban", imType, chatID); err != nil {
+		return nil, err
+	}
+	state := &MuteState{Imtype: imType, ChatID: chatID}
+	return state, BucketDelete(muteBucket, imType+":"+chatID)
+}
+
+/**
+ * @description: 获取指定群的全员禁言状态，已到期但未解除的禁言会立即解除
+ * @param {string} imType 平台类型
+ * @param {string} chatID 群组ID
+ * @return {*MuteState}
+ */
+func GroupMuteState(imType, chatID string) (*MuteState, error) {
+	state := loadMuteState(imType + ":" + chatID)
+	if state == nil {
+		return &MuteState{Imtype: imType, ChatID: chatID}, nil
+	}
+	if state.Until > 0 && time.Now().Unix() >= state.Until {
+		return GroupUnmute(imType, chatID)
+	}
+	return state, nil
+}
+
+/**
+ * @description: 恢复已保存的全员禁言自动解除计划，在插件启动时调用
+ */
+func ResumeGroupMutes() {
+	for _, key := range BucketAllKeys(muteBucket) {
+		if state := loadMuteState(key); state != nil {
+			scheduleUnmute(state)
+		}
+	}
+}
+
+func groupWholeBan(path, imType, chatID string) error {
+	params := map[string]interface{}{
+		"imType": imType,
+		"chatid": chatID,
+	}
+	body, _ := json.Marshal(params)
+	_, err := httplib.Post(sockUrl()+path).Header("Content-Type", "application/json").Body(body).SetTransport(transport).Bytes()
+	return err
+}
+
+func loadMuteState(key string) *MuteState {
+	data := BucketGet(muteBucket, key)
+	if data == "" {
+		return nil
+	}
+	state := &MuteState{}
+	if json.Unmarshal([]byte(data), state) != nil {
+		return nil
+	}
+	return state
+}
+
+func saveMuteState(state *MuteState) error {
+	data, _ := json.Marshal(state)
+	return BucketSet(muteBucket, state.Imtype+":"+state.ChatID, string(data))
+}
+
+func scheduleUnmute(state *MuteState) {
+	key := state.Imtype + ":" + state.ChatID
+	stopUnmute(key)
+	if state.Until == 0 {
+		return
+	}
+	imType, chatID := state.Imtype, state.ChatID
+	timer := time.AfterFunc(time.Until(time.Unix(state.Until, 0)), func() {
+		GroupUnmute(imType, chatID)
+	})
+	muteTimers.Lock()
+	muteTimers.items[key] = timer
+	muteTimers.Unlock()
+}
+
+func stopUnmute(key string) {
+	muteTimers.Lock()
+	defer muteTimers.Unlock()
+	if timer, ok := muteTimers.items[key]; ok {
+		timer.Stop()
+		delete(muteTimers.items, key)
+	}
+}
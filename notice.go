@@ -0,0 +1,97 @@
+package middleware
+
+import "time"
+
+// 通知事件类型
+const (
+	EventMemberJoin  = "member_join"
+	EventMemberLeave = "member_leave"
+	EventRecall      = "recall"
+	EventMute        = "mute"
+	EventAdminChange = "admin_change"
+	EventPoke        = "poke"
+)
+
+/**
+ * @description: 新成员入群事件
+ */
+type MemberJoinEvent struct {
+	Imtype     string `json:"imtype"`
+	ChatID     string `json:"chatid"`
+	UserID     string `json:"userid"`
+	OperatorID string `json:"operatorid"` // 同意入群或邀请者
+}
+
+func (e *MemberJoinEvent) EventType() string { return EventMemberJoin }
+
+/**
+ * @description: 成员退群事件
+ */
+type MemberLeaveEvent struct {
+	Imtype     string `json:"imtype"`
+	ChatID     string `json:"chatid"`
+	UserID     string `json:"userid"`
+	OperatorID string `json:"operatorid"` // 被踢出时为操作者
+	Kicked     bool   `json:"kicked"`     // 是否被踢出
+}
+
+func (e *MemberLeaveEvent) EventType() string { return EventMemberLeave }
+
+/**
+ * @description: 消息撤回事件
+ */
+type RecallEvent struct {
+	Imtype     string `json:"imtype"`
+	ChatID     string `json:"chatid"`
+	UserID     string `json:"userid"` // 消息发送者
+	OperatorID string `json:"operatorid"`
+	MessageID  string `json:"messageid"`
+	Content    string `json:"content"` // 被撤回的消息内容，平台不提供时为空
+}
+
+func (e *RecallEvent) EventType() string { return EventRecall }
+
+/**
+ * @description: 禁言事件，UserID为空时为全员禁言
+ */
+type MuteEvent struct {
+	Imtype     string `json:"imtype"`
+	ChatID     string `json:"chatid"`
+	UserID     string `json:"userid"`
+	OperatorID string `json:"operatorid"`
+	Seconds    int64  `json:"duration"` // 禁言时长，单位：秒，为0时表示解除禁言
+}
+
+func (e *MuteEvent) EventType() string { return EventMute }
+
+/**
+ * @description: 禁言时长
+ * @return {time.Duration}
+ */
+func (e *MuteEvent) Duration() time.Duration {
+	return time.Duration(e.Seconds) * time.Second
+}
+
+/**
+ * @description: 管理员变动事件
+ */
+type AdminChangeEvent struct {
+	Imtype string `json:"imtype"`
+	ChatID string `json:"chatid"`
+	UserID string `json:"userid"`
+	Set    bool   `json:"set"` // true为设置管理员，false为取消管理员
+}
+
+func (e *AdminChangeEvent) EventType() string { return EventAdminChange }
+
+/**
+ * @description: 戳一戳事件
+ */
+type PokeEvent struct {
+	Imtype   string `json:"imtype"`
+	ChatID   string `json:"chatid"`
+	UserID   string `json:"userid"`
+	TargetID string `json:"targetid"`
+}
+
+func (e *PokeEvent) EventType() string { return EventPoke }
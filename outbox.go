@@ -0,0 +1,395 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+// 发件箱消息类型
+const (
+	OutboxPush  = "push"
+	OutboxReply = "reply"
+)
+
+// 发件箱列表
+const (
+	outboxPending = "pending"
+	outboxDead    = "dead"
+)
+
+var ErrOutboxNotFound = errors.New("发件箱中不存在该消息")
+
+/**
+ * @description: 发件箱消息
+ */
+type OutboxMessage struct {
+	ID        string `json:"id"`
+	Kind      string `json:"kind"` // push/reply
+	SenderID  string `json:"senderid,omitempty"`
+	ImType    string `json:"imType,omitempty"`
+	GroupCode string `json:"groupCode,omitempty"`
+	UserID    string `json:"userID,omitempty"`
+	Title     string `json:"title,omitempty"`
+	Content   string `json:"content"`
+	Attempts  int    `json:"attempts"`  // 已尝试次数
+	NextAt    int64  `json:"nextAt"`    // 下次投递时间，unix时间戳，单位：毫秒
+	LastError string `json:"lastError"` // 最近一次投递失败的原因
+	CreatedAt int64  `json:"createdAt"` // 入队时间，unix时间戳，单位：毫秒
+}
+
+/**
+ * @description: 发件箱存储，list为pending（待投递）或dead（死信）
+ */
+type OutboxStore interface {
+	Load(list string) ([]OutboxMessage, error)
+	Save(list string, msgs []OutboxMessage) error
+}
+
+/**
+ * @description: 本地文件存储，autMan重启期间仍可读写，推荐使用
+ */
+type FileStore struct {
+	Path string
+}
+
+func (f *FileStore) Load(list string) ([]OutboxMessage, error) {
+	lists, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+	return lists[list], nil
+}
+
+func (f *FileStore) Save(list string, msgs []OutboxMessage) error {
+	lists, err := f.read()
+	if err != nil {
+		return err
+	}
+	lists[list] = msgs
+	data, _ := json.MarshalIndent(lists, "", "  ")
+	tmp := f.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.Path)
+}
+
+func (f *FileStore) read() (map[string][]OutboxMessage, error) {
+	lists := map[string][]OutboxMessage{}
+	data, err := os.ReadFile(f.Path)
+	if os.IsNotExist(err) {
+		return lists, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &lists); err != nil {
+			return nil, err
+		}
+	}
+	return lists, nil
+}
+
+/**
+ * @description: 数据桶存储，autMan不可用时无法读写，仅适合在autMan运行期间做失败重试
+ */
+type BucketStore struct {
+	Bucket string
+}
+
+func (b *BucketStore) Load(list string) ([]OutboxMessage, error) {
+	var msgs []OutboxMessage
+	if data := BucketGet(b.Bucket, list); data != "" {
+		if err := json.Unmarshal([]byte(data), &msgs); err != nil {
+			return nil, err
+		}
+	}
+	return msgs, nil
+}
+
+func (b *BucketStore) Save(list string, msgs []OutboxMessage) error {
+	data, _ := json.Marshal(msgs)
+	return BucketSet(b.Bucket, list, string(data))
+}
+
+/**
+ * @description: 发件箱，发送失败的消息持久化后按指数退避重试，超过最大次数后转入死信
+ */
+type Outbox struct {
+	Store       OutboxStore
+	MaxAttempts int           // 最大投递次数，默认8
+	BaseDelay   time.Duration // 首次重试间隔，默认2秒
+	MaxDelay    time.Duration // 最大重试间隔，默认5分钟
+	Tick        time.Duration // Run检查间隔，默认1秒
+	mu          sync.Mutex    // 保护存储的读写
+	flushMu     sync.Mutex    // 串行化Flush，避免同一消息被重复投递
+}
+
+/**
+ * @description: 创建发件箱
+ * @param {OutboxStore} store 存储，如&FileStore{Path: "outbox.json"}
+ * @return {*Outbox}
+ */
+func NewOutbox(store OutboxStore) *Outbox {
+	return &Outbox{
+		Store:       store,
+		MaxAttempts: 8,
+		BaseDelay:   2 * time.Second,
+		MaxDelay:    5 * time.Minute,
+		Tick:        time.Second,
+	}
+}
+
+/**
+ * @description: 推送消息，发送失败时放入发件箱等待重试
+ * @param {string} imtType 包括：qq/qb/wx/wb/tg/tb/wxmp/wxsv
+ * @param {string} groupCode 群号
+ * @param {string} userID 用户ID
+ * @param {string} title 标题
+ * @param {string} content 内容
+ * @return {error} 入队失败时返回错误，发送失败但入队成功时返回nil
+ */
+func (o *Outbox) Push(imType, groupCode, userID, title, content string) error {
+	return o.send(OutboxMessage{Kind: OutboxPush, ImType: imType, GroupCode: groupCode, UserID: userID, Title: title, Content: content})
+}
+
+/**
+ * @description: 回复文本，发送失败时放入发件箱等待重试
+ * 触发时的会话在autMan重启后失效，因此入队时记录平台、群号和用户ID，重试时通过Push投递
+ * @param {*Sender} s 当前用户
+ * @param {string} text 文本内容
+ * @return {error} 入队失败时返回错误，发送失败但入队成功时返回nil
+ */
+func (o *Outbox) Reply(s *Sender, text string) error {
+	// 会话信息与回复走同一连接，须在回复前读取，否则autMan不可用时只能得到空值
+	msg := OutboxMessage{Kind: OutboxReply, SenderID: s.SenderID, Content: text}
+	msg.ImType, msg.UserID = s.GetImtype(), s.GetUserID()
+	if chatID := s.GetChatID(); !isPrivateChat(chatID) {
+		msg.GroupCode = chatID
+	}
+	_, err := s.Reply(text)
+	if err == nil {
+		return nil
+	}
+	msg.LastError = err.Error()
+	return o.retry(msg)
+}
+
+func (o *Outbox) send(msg OutboxMessage) error {
+	err := deliver(msg)
+	if err == nil {
+		return nil
+	}
+	msg.LastError = err.Error()
+	return o.retry(msg)
+}
+
+// 首次发送失败后入队，等待第一次重试
+func (o *Outbox) retry(msg OutboxMessage) error {
+	msg.Attempts = 1
+	msg.NextAt = time.Now().Add(o.delay(1)).UnixMilli()
+	return o.Enqueue(msg)
+}
+
+/**
+ * @description: 将消息放入发件箱，由Run或Flush投递
+ * @param {OutboxMessage} msg 消息
+ */
+func (o *Outbox) Enqueue(msg OutboxMessage) error {
+	if msg.ID == "" {
+		msg.ID = newJobID()
+	}
+	if msg.CreatedAt == 0 {
+		msg.CreatedAt = time.Now().UnixMilli()
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	pending, err := o.Store.Load(outboxPending)
+	if err != nil {
+		return err
+	}
+	return o.Store.Save(outboxPending, append(pending, msg))
+}
+
+/**
+ * @description: 运行发件箱，定期投递到期的消息，阻塞直到exitChannel关闭
+ */
+func (o *Outbox) Run(exitChannel chan struct{}) {
+	tick := o.Tick
+	if tick <= 0 {
+		tick = time.Second
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-exitChannel:
+			return
+		case <-ticker.C:
+			o.Flush()
+		}
+	}
+}
+
+/**
+ * @description: 立即投递所有到期的消息，同一发件箱的多次Flush依次执行
+ * 多个进程共用同一存储时仍可能重复投递，应只在一个进程中运行Run
+ * @return {int} 投递成功的数量
+ */
+func (o *Outbox) Flush() (int, error) {
+	o.flushMu.Lock()
+	defer o.flushMu.Unlock()
+	o.mu.Lock()
+	pending, err := o.Store.Load(outboxPending)
+	o.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now().UnixMilli()
+	results := map[string]error{}
+	for _, msg := range pending {
+		if msg.NextAt <= now {
+			results[msg.ID] = deliver(msg)
+		}
+	}
+	if len(results) == 0 {
+		return 0, nil
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	// 投递期间可能有新消息入队，重新读取后合并结果
+	if pending, err = o.Store.Load(outboxPending); err != nil {
+		return 0, err
+	}
+	dead, err := o.Store.Load(outboxDead)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	remain := pending[:0]
+	for _, msg := range pending {
+		err, ok := results[msg.ID]
+		switch {
+		case !ok:
+			remain = append(remain, msg)
+		case err == nil:
+			sent++
+		default:
+			msg.Attempts++
+			msg.LastError = err.Error()
+			if msg.Attempts >= o.maxAttempts() {
+				dead = append(dead, msg)
+				continue
+			}
+			msg.NextAt = time.Now().Add(o.delay(msg.Attempts)).UnixMilli()
+			remain = append(remain, msg)
+		}
+	}
+	if err := o.Store.Save(outboxDead, dead); err != nil {
+		return sent, err
+	}
+	return sent, o.Store.Save(outboxPending, remain)
+}
+
+/**
+ * @description: 获取待投递的消息
+ * @return {[]OutboxMessage}
+ */
+func (o *Outbox) Pending() ([]OutboxMessage, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.Store.Load(outboxPending)
+}
+
+/**
+ * @description: 获取死信消息
+ * @return {[]OutboxMessage}
+ */
+func (o *Outbox) DeadLetters() ([]OutboxMessage, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.Store.Load(outboxDead)
+}
+
+/**
+ * @description: 将死信消息重新放入待投递列表，重置尝试次数
+ * @param {string} id 消息ID
+ */
+func (o *Outbox) Replay(id string) error {
+	return o.takeDead(id, true)
+}
+
+/**
+ * @description: 删除死信消息
+ * @param {string} id 消息ID
+ */
+func (o *Outbox) Discard(id string) error {
+	return o.takeDead(id, false)
+}
+
+func (o *Outbox) takeDead(id string, replay bool) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	dead, err := o.Store.Load(outboxDead)
+	if err != nil {
+		return err
+	}
+	for i, msg := range dead {
+		if msg.ID != id {
+			continue
+		}
+		if replay {
+			pending, err := o.Store.Load(outboxPending)
+			if err != nil {
+				return err
+			}
+			msg.Attempts, msg.NextAt = 0, 0
+			if err := o.Store.Save(outboxPending, append(pending, msg)); err != nil {
+				return err
+			}
+		}
+		return o.Store.Save(outboxDead, append(dead[:i], dead[i+1:]...))
+	}
+	return ErrOutboxNotFound
+}
+
+func (o *Outbox) maxAttempts() int {
+	if o.MaxAttempts > 0 {
+		return o.MaxAttempts
+	}
+	return 8
+}
+
+// 第attempts次失败后的重试间隔
+func (o *Outbox) delay(attempts int) time.Duration {
+	d, maxDelay := o.BaseDelay, o.MaxDelay
+	if d <= 0 {
+		d = 2 * time.Second
+	}
+	if maxDelay <= 0 {
+		maxDelay = 5 * time.Minute
+	}
+	for i := 1; i < attempts && d < maxDelay; i++ {
+		d *= 2
+	}
+	if d > maxDelay {
+		d = maxDelay
+	}
+	return d
+}
+
+func deliver(msg OutboxMessage) error {
+	switch {
+	case msg.Kind == OutboxReply && msg.ImType == "":
+		// 未记录会话信息的回复只能通过原会话投递
+		_, err := (&Sender{SenderID: msg.SenderID}).Reply(msg.Content)
+		return err
+	default:
+		return Push(msg.ImType, msg.GroupCode, msg.UserID, msg.Title, msg.Content)
+	}
+}
@@ -0,0 +1,295 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/beego/beego/v2/client/httplib"
+	"github.com/buger/jsonparser"
+)
+
+var ErrParamMissing = errors.New("缺少参数")
+
+// autMan未提供/params且未指定rule时，逐个调用Param获取的数量
+const maxParams = 32
+
+/**
+ * @description: 规则匹配到的全部参数，索引从1开始，与Param一致
+ */
+type Captures struct {
+	Values []string
+	Names  []string // 与Values一一对应，未命名的参数为空
+}
+
+/**
+ * @description: 一次获取用户触发的全部参数，避免多次调用Param
+ * @param {string} rule 可选，头注中的rule规则，其中的命名分组(?P<name>...)会映射为参数名称
+ * @return {*Captures}
+ */
+func (s *Sender) Params(rule ...string) (*Captures, error) {
+	params := map[string]interface{}{
+		"senderid": s.SenderID,
+	}
+	body, _ := json.Marshal(params)
+	resp, err := httplib.Post(sockUrl()+"/params").Header("Content-Type", "application/json").Body(body).SetTransport(transport).Bytes()
+	if err != nil {
+		return nil, err
+	}
+	c := &Captures{}
+	if data, typ, _, err := jsonparser.Get(resp, "data"); err == nil && typ == jsonparser.Array {
+		json.Unmarshal(data, &c.Values)
+	} else {
+		// 旧版本autMan不支持/params，逐个获取。可选分组未匹配时参数为空，不能据此判断参数已取完：
+		// 提供rule时按其中的分组数量获取，否则获取maxParams个后去掉末尾的空参数
+		count := maxParams
+		if len(rule) > 0 {
+			count = len(ParamNames(rule[0]))
+		}
+		for i := 1; i <= count; i++ {
+			c.Values = append(c.Values, s.Param(i))
+		}
+		if len(rule) == 0 {
+			for len(c.Values) > 0 && c.Values[len(c.Values)-1] == "" {
+				c.Values = c.Values[:len(c.Values)-1]
+			}
+		}
+	}
+	if data, typ, _, err := jsonparser.Get(resp, "names"); err == nil && typ == jsonparser.Array {
+		json.Unmarshal(data, &c.Names)
+	} else if len(rule) > 0 {
+		c.Names = ParamNames(rule[0])
+	}
+	return c, nil
+}
+
+/**
+ * @description: 获取rule规则中各参数的名称，未命名的参数为空，规则按CompileRule编译
+ * @param {string} rule 头注中的rule规则
+ * @return {[]string} 长度即参数数量，规则无效时为nil
+ */
+func ParamNames(rule string) []string {
+	re, err := CompileRule(rule)
+	if err != nil {
+		return nil
+	}
+	return re.SubexpNames()[1:]
+}
+
+/**
+ * @description: 编译触发规则。以"raw "开头或以"^"开头的规则按正则处理，
+ * 否则为关键词规则，其中的"?"匹配任意内容并作为参数，其余字符按原文匹配
+ * @param {string} rule 触发规则
+ * @return {*regexp.Regexp}
+ */
+func CompileRule(rule string) (*regexp.Regexp, error) {
+	expr := strings.TrimSpace(rule)
+	switch {
+	case strings.HasPrefix(expr, "raw "):
+		expr = strings.TrimSpace(strings.TrimPrefix(expr, "raw "))
+	case strings.HasPrefix(expr, "^"):
+	default:
+		parts := strings.Split(expr, "?")
+		for i := range parts {
+			parts[i] = regexp.QuoteMeta(parts[i])
+		}
+		expr = "^" + strings.Join(parts, "(.+?)") + "$"
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("规则%s不是合法的正则: %w", rule, err)
+	}
+	return re, nil
+}
+
+/**
+ * @description: 参数数量
+ * @return {int}
+ */
+func (c *Captures) Len() int {
+	return len(c.Values)
+}
+
+/**
+ * @description: 按索引获取参数
+ * @param {int} index 参数索引，从1开始
+ * @return {string}
+ */
+func (c *Captures) Get(index int) (string, error) {
+	if index < 1 || index > len(c.Values) || c.Values[index-1] == "" {
+		return "", fmt.Errorf("%w%d", ErrParamMissing, index)
+	}
+	return c.Values[index-1], nil
+}
+
+/**
+ * @description: 按名称获取参数
+ * @param {string} name 参数名称，即rule规则中(?P<name>...)的name
+ * @return {string}
+ */
+func (c *Captures) Named(name string) (string, error) {
+	for i, n := range c.Names {
+		if n == name && i < len(c.Values) && c.Values[i] != "" {
+			return c.Values[i], nil
+		}
+	}
+	return "", fmt.Errorf("%w%s", ErrParamMissing, name)
+}
+
+/**
+ * @description: 按名称获取整数参数，名称不存在时按索引解析，如"1"
+ * @param {string} key 参数名称或索引
+ * @return {int}
+ */
+func (c *Captures) Int(key string) (int, error) {
+	v, err := c.lookup(key)
+	if err != nil {
+		return 0, err
+	}
+	return paramInt(key, v)
+}
+
+/**
+ * @description: 按名称获取小数参数，名称不存在时按索引解析
+ * @param {string} key 参数名称或索引
+ * @return {float64}
+ */
+func (c *Captures) Float(key string) (float64, error) {
+	v, err := c.lookup(key)
+	if err != nil {
+		return 0, err
+	}
+	return paramFloat(key, v)
+}
+
+/**
+ * @description: 按名称获取时长参数，名称不存在时按索引解析，支持30s、5m、10分钟等写法
+ * @param {string} key 参数名称或索引
+ * @return {time.Duration}
+ */
+func (c *Captures) Duration(key string) (time.Duration, error) {
+	v, err := c.lookup(key)
+	if err != nil {
+		return 0, err
+	}
+	return paramDuration(key, v)
+}
+
+/**
+ * @description: 按名称获取用户参数，名称不存在时按索引解析，支持[CQ:at,qq=123]、@123和123
+ * @param {string} key 参数名称或索引
+ * @return {string} 用户ID
+ */
+func (c *Captures) User(key string) (string, error) {
+	v, err := c.lookup(key)
+	if err != nil {
+		return "", err
+	}
+	return paramUser(key, v)
+}
+
+func (c *Captures) lookup(key string) (string, error) {
+	if containsString(c.Names, key) {
+		return c.Named(key)
+	}
+	if index, err := strconv.Atoi(key); err == nil {
+		return c.Get(index)
+	}
+	return "", fmt.Errorf("%w%s", ErrParamMissing, key)
+}
+
+/**
+ * @description: 获取整数参数
+ * @param {int} index 参数索引
+ * @return {int}
+ */
+func (s *Sender) ParamInt(index int) (int, error) {
+	v, err := s.param(index)
+	if err != nil {
+		return 0, err
+	}
+	return paramInt(strconv.Itoa(index), v)
+}
+
+/**
+ * @description: 获取小数参数
+ * @param {int} index 参数索引
+ * @return {float64}
+ */
+func (s *Sender) ParamFloat(index int) (float64, error) {
+	v, err := s.param(index)
+	if err != nil {
+		return 0, err
+	}
+	return paramFloat(strconv.Itoa(index), v)
+}
+
+/**
+ * @description: 获取时长参数，支持30s、5m、10分钟等写法
+ * @param {int} index 参数索引
+ * @return {time.Duration}
+ */
+func (s *Sender) ParamDuration(index int) (time.Duration, error) {
+	v, err := s.param(index)
+	if err != nil {
+		return 0, err
+	}
+	return paramDuration(strconv.Itoa(index), v)
+}
+
+/**
+ * @description: 获取用户参数，支持[CQ:at,qq=123]、@123和123
+ * @param {int} index 参数索引
+ * @return {string} 用户ID
+ */
+func (s *Sender) ParamUser(index int) (string, error) {
+	v, err := s.param(index)
+	if err != nil {
+		return "", err
+	}
+	return paramUser(strconv.Itoa(index), v)
+}
+
+func (s *Sender) param(index int) (string, error) {
+	v := s.Param(index)
+	if v == "" {
+		return "", fmt.Errorf("%w%d", ErrParamMissing, index)
+	}
+	return v, nil
+}
+
+func paramInt(key, v string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil {
+		return 0, fmt.Errorf("参数%s不是整数: %s", key, v)
+	}
+	return n, nil
+}
+
+func paramFloat(key, v string) (float64, error) {
+	n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	if err != nil {
+		return 0, fmt.Errorf("参数%s不是数字: %s", key, v)
+	}
+	return n, nil
+}
+
+func paramDuration(key, v string) (time.Duration, error) {
+	d, err := ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("参数%s无效: %w", key, err)
+	}
+	return d, nil
+}
+
+func paramUser(key, v string) (string, error) {
+	u, err := ParseUser(v)
+	if err != nil {
+		return "", fmt.Errorf("参数%s无效: %w", key, err)
+	}
+	return u, nil
+}
@@ -0,0 +1,214 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrPayTimeout  = fmt.Errorf("等待支付超时: %w", ErrTimeout)
+	ErrPayCanceled = fmt.Errorf("用户取消支付: %w", ErrCanceled)
+	ErrPayBusy     = errors.New("当前用户正在等待支付")
+	ErrPayNoOrder  = errors.New("支付结果缺少订单号，无法幂等入账")
+)
+
+// 支付状态
+const (
+	PayStatusSuccess = "success"
+	PayStatusTimeout = "timeout"
+	PayStatusCancel  = "cancel"
+)
+
+/**
+ * @description: 支付结果
+ */
+type PaymentResult struct {
+	Amount  float64 `json:"amount"`  // 金额，单位：元
+	Payer   string  `json:"payer"`   // 付款人
+	OrderID string  `json:"orderId"` // 订单号
+	Channel string  `json:"channel"` // 支付渠道，如wxpay/alipay
+	Status  string  `json:"status"`  // 支付状态：success/timeout/cancel
+	Raw     string  `json:"-"`       // WaitPay返回的原始内容
+}
+
+/**
+ * @description: 解析WaitPay返回的支付信息，兼容常见的字段命名，必须包含status或state字段
+ * @param {string} raw WaitPay返回的json字符串
+ * @param {string} exitCode 退出码，用户发送退出码时WaitPay会原样返回
+ * @return {*PaymentResult}
+ */
+func ParsePaymentResult(raw, exitCode string) (*PaymentResult, error) {
+	result := &PaymentResult{Raw: raw}
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		result.Status = PayStatusTimeout
+		return result, nil
+	}
+	if exitCode != "" && raw == exitCode {
+		result.Status = PayStatusCancel
+		return result, nil
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(raw), &fields); err != nil {
+		return nil, fmt.Errorf("无法解析的支付信息: %s", raw)
+	}
+	pick := func(keys ...string) string {
+		for _, k := range keys {
+			switch v := fields[k].(type) {
+			case string:
+				if v != "" {
+					return v
+				}
+			case float64:
+				return strconv.FormatFloat(v, 'f', -1, 64)
+			case bool:
+				return strconv.FormatBool(v)
+			}
+		}
+		return ""
+	}
+	result.Amount, _ = strconv.ParseFloat(pick("amount", "money", "price", "total"), 64)
+	result.Payer = pick("payer", "userId", "userid", "user", "nickname")
+	result.OrderID = pick("orderId", "orderid", "order_id", "tradeNo", "trade_no")
+	result.Channel = pick("channel", "payType", "pay_type", "type")
+	status := strings.ToLower(pick("status", "state"))
+	switch status {
+	case "":
+		return nil, fmt.Errorf("支付信息缺少状态: %s", raw)
+	case "success", "paid", "ok", "true":
+		result.Status = PayStatusSuccess
+	case "timeout":
+		result.Status = PayStatusTimeout
+	case "cancel", "canceled", "cancelled", "exit":
+		result.Status = PayStatusCancel
+	default:
+		result.Status = status
+	}
+	return result, nil
+}
+
+/**
+ * @description: 等待用户支付并解析支付结果，超时返回ErrPayTimeout，用户发送退出码返回ErrPayCanceled
+ * @param {string} exitCode 退出码
+ * @param {time.Duration} timeout 超时
+ * @return {*PaymentResult}
+ */
+func (s *Sender) WaitPayment(exitCode string, timeout time.Duration) (*PaymentResult, error) {
+	result, err := ParsePaymentResult(s.WaitPay(exitCode, int(timeout.Milliseconds())), exitCode)
+	if err != nil {
+		return nil, err
+	}
+	switch result.Status {
+	case PayStatusTimeout:
+		return result, ErrPayTimeout
+	case PayStatusCancel:
+		return result, ErrPayCanceled
+	case PayStatusSuccess:
+		return result, nil
+	}
+	return result, fmt.Errorf("支付未成功: %s", result.Status)
+}
+
+/**
+ * @description: 支付流程：发送提示、等待支付、按订单号幂等入账
+ */
+type PaymentSession struct {
+	Prompt   string        // 支付提示语，为空时不发送
+	ExitCode string        // 用户发送该内容时取消支付，默认"q"
+	Timeout  time.Duration // 等待支付超时，默认5分钟
+	Ledger   string        // 入账数据桶，默认"payment"
+}
+
+/**
+ * @description: 运行支付流程，支付成功后将金额计入付款用户的余额，同一订单只入账一次，没有订单号时返回ErrPayNoOrder
+ * @param {*Sender} s 当前用户
+ * @return {*PaymentResult}
+ */
+func (p *PaymentSession) Run(s *Sender) (*PaymentResult, error) {
+	if s.AtWaitPay() {
+		return nil, ErrPayBusy
+	}
+	exitCode := p.ExitCode
+	if exitCode == "" {
+		exitCode = "q"
+	}
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Minute
+	}
+	if p.Prompt != "" {
+		if _, err := s.Reply(p.Prompt); err != nil {
+			return nil, err
+		}
+	}
+	result, err := s.WaitPayment(exitCode, timeout)
+	if err != nil {
+		return result, err
+	}
+	userID := s.GetUserID()
+	if result.Payer == "" {
+		result.Payer = userID
+	}
+	return result, p.Credit(userID, result)
+}
+
+/**
+ * @description: 按订单号幂等入账，订单已入账时直接返回，没有订单号时返回ErrPayNoOrder且不入账
+ * 余额与已入账的订单号保存在同一个值中，一次写入同时生效，写入失败时可以安全重试
+ * 注意：读取与写入之间没有跨进程的锁，autMan每次触发插件都是独立进程，同一用户的入账并发执行时仍可能丢失或重复
+ * @param {string} userID 入账用户ID
+ * @param {*PaymentResult} result 支付结果
+ */
+func (p *PaymentSession) Credit(userID string, result *PaymentResult) error {
+	if result.OrderID == "" {
+		return ErrPayNoOrder
+	}
+	creditMu.Lock()
+	defer creditMu.Unlock()
+	acc := p.account(userID)
+	if containsString(acc.Orders, result.OrderID) {
+		return nil
+	}
+	acc.Balance += result.Amount
+	acc.Orders = append(acc.Orders, result.OrderID)
+	data, _ := json.Marshal(acc)
+	return BucketSet(p.ledger(), "account:"+userID, string(data))
+}
+
+// 仅串行化同一进程内的入账，不能防止多个插件进程并发入账
+var creditMu sync.Mutex
+
+// 用户账户，余额与已入账订单号一起保存
+type paymentAccount struct {
+	Balance float64  `json:"balance"`
+	Orders  []string `json:"orders"`
+}
+
+func (p *PaymentSession) account(userID string) paymentAccount {
+	var acc paymentAccount
+	if data := BucketGet(p.ledger(), "account:"+userID); data != "" {
+		json.Unmarshal([]byte(data), &acc)
+	}
+	return acc
+}
+
+/**
+ * @description: 查询用户在入账数据桶中的余额
+ * @param {string} userID 用户ID
+ * @return {float64}
+ */
+func (p *PaymentSession) Balance(userID string) float64 {
+	return p.account(userID).Balance
+}
+
+func (p *PaymentSession) ledger() string {
+	if p.Ledger != "" {
+		return p.Ledger
+	}
+	return "payment"
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import "testing"
+
+func TestParsePaymentResult(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		status  string
+		amount  float64
+		orderID string
+		wantErr bool
+	}{
+		{"超时", "", PayStatusTimeout, 0, "", false},
+		{"退出码", "q", PayStatusCancel, 0, "", false},
+		{"成功", `{"status":"success","amount":9.9,"orderId":"A1"}`, PayStatusSuccess, 9.9, "A1", false},
+		{"兼容字段名", `{"state":"PAID","money":"5","trade_no":"T2"}`, PayStatusSuccess, 5, "T2", false},
+		{"取消", `{"status":"cancelled"}`, PayStatusCancel, 0, "", false},
+		{"未知状态", `{"status":"refunded","orderId":"A3"}`, "refunded", 0, "A3", false},
+		{"缺少状态", `{"error":"failed"}`, "", 0, "", true},
+		{"不使用id作为订单号", `{"status":"success","id":"12345"}`, PayStatusSuccess, 0, "", false},
+		{"非json", "not json", "", 0, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePaymentResult(tt.raw, "q")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePaymentResult(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.Status != tt.status || got.Amount != tt.amount || got.OrderID != tt.orderID {
+				t.Errorf("ParsePaymentResult(%q) = %+v, want status %q amount %v order %q", tt.raw, got, tt.status, tt.amount, tt.orderID)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+/**
+ * @description: 各消息平台的能力描述
+ */
+type Platform struct {
+	CQCode    bool // 原生支持CQ码
+	Markdown  bool // 原生支持markdown
+	HTML      bool // 支持HTML格式文本，如telegram
+	MaxLength int  // 单条消息最大字符数，为0时使用DefaultMaxLength
+	Image     bool // 支持发送图片
+	Voice     bool // 支持发送语音
+	Video     bool // 支持发送视频
+	File      bool // 支持发送文件
+	Edit      bool // 支持编辑已发送的消息
+	Buttons   bool // 支持按钮、内联键盘
+	Quote     bool // 支持原生引用回复，CQ码平台使用[CQ:reply]
+	Group     bool // 支持群管理
+}
+
+/**
+ * @description: 未声明长度限制的平台使用的单条消息最大字符数
+ */
+var DefaultMaxLength = 2000
+
+/**
+ * @description: 已知平台能力表，键为imtype：qq/qb/wx/wb/tg/tb/wxmp/wxsv
+ */
+var Platforms = map[string]Platform{
+	"qq":   {CQCode: true, MaxLength: 4500, Image: true, Voice: true, Video: true, File: true, Quote: true, Group: true},
+	"qb":   {CQCode: true, Markdown: true, MaxLength: 2000, Image: true, Voice: true, Video: true, File: true, Buttons: true, Quote: true, Group: true},
+	"wx":   {MaxLength: 2000, Image: true, Voice: true, Video: true, File: true, Group: true},
+	"wb":   {MaxLength: 5000, Image: true},
+	"tg":   {HTML: true, MaxLength: 4096, Image: true, Voice: true, Video: true, File: true, Edit: true, Buttons: true, Quote: true, Group: true},
+	"tb":   {HTML: true, MaxLength: 4096, Image: true, Voice: true, Video: true, File: true, Edit: true, Buttons: true, Quote: true, Group: true},
+	"wxmp": {MaxLength: 600, Image: true, Voice: true},
+	"wxsv": {MaxLength: 2000, Image: true, Voice: true, Video: true},
+}
+
+/**
+ * @description: 获取平台能力，未知平台按纯文本平台处理
+ * @param {string} imtype 平台类型
+ * @return {Platform}
+ */
+func PlatformOf(imtype string) Platform {
+	return Platforms[imtype]
+}
+
+/**
+ * @description: 获取平台单条消息最大字符数
+ * @param {string} imtype 平台类型
+ * @return {int}
+ */
+func MaxLengthOf(imtype string) int {
+	if n := PlatformOf(imtype).MaxLength; n > 0 {
+		return n
+	}
+	return DefaultMaxLength
+}
@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/beego/beego/v2/client/httplib"
+	"github.com/buger/jsonparser"
+)
+
+/**
+ * @description: 引用指定消息回复文本
+ * @param {string} messageid 被引用的消息ID，为空时引用触发插件的消息
+ * @param {string} text 文本内容
+ * @return {[]string} 消息ID
+ */
+func (s *Sender) ReplyTo(messageid, text string) ([]string, error) {
+	messageid = s.quoteID(messageid)
+	p := PlatformOf(s.GetImtype())
+	if p.CQCode {
+		return s.Reply(quoteMessage(messageid).String() + text)
+	}
+	return s.sendQuoted("/sendText", "text", text, messageid, p)
+}
+
+/**
+ * @description: 引用指定消息回复markdown
+ * @param {string} messageid 被引用的消息ID，为空时引用触发插件的消息
+ * @param {string} text markdown字符串
+ * @return {[]string} 消息ID
+ */
+func (s *Sender) ReplyMarkdownTo(messageid, text string) ([]string, error) {
+	messageid = s.quoteID(messageid)
+	return s.sendQuoted("/sendMarkdown", "markdown", text, messageid, PlatformOf(s.GetImtype()))
+}
+
+/**
+ * @description: 引用指定消息回复图片
+ * @param {string} messageid 被引用的消息ID，为空时引用触发插件的消息
+ * @param {Media} src 媒体来源
+ * @return {[]string} 消息ID
+ */
+func (s *Sender) ReplyImageTo(messageid string, src Media) ([]string, error) {
+	return s.replyMediaTo(messageid, MediaImage, src)
+}
+
+/**
+ * @description: 引用指定消息回复语音
+ * @param {string} messageid 被引用的消息ID，为空时引用触发插件的消息
+ * @param {Media} src 媒体来源
+ * @return {[]string} 消息ID
+ */
+func (s *Sender) ReplyVoiceTo(messageid string, src Media) ([]string, error) {
+	return s.replyMediaTo(messageid, MediaVoice, src)
+}
+
+/**
+ * @description: 引用指定消息回复视频
+ * @param {string} messageid 被引用的消息ID，为空时引用触发插件的消息
+ * @param {Media} src 媒体来源
+ * @return {[]string} 消息ID
+ */
+func (s *Sender) ReplyVideoTo(messageid string, src Media) ([]string, error) {
+	return s.replyMediaTo(messageid, MediaVideo, src)
+}
+
+func (s *Sender) replyMediaTo(messageid, kind string, src Media) ([]string, error) {
+	payload, err := s.mediaPayload(kind, src)
+	if err != nil {
+		return nil, err
+	}
+	messageid = s.quoteID(messageid)
+	p := PlatformOf(s.GetImtype())
+	if p.CQCode {
+		m := quoteMessage(messageid)
+		switch kind {
+		case MediaImage:
+			m.Image(payload)
+		case MediaVoice:
+			m.Record(payload)
+		case MediaVideo:
+			m.Video(payload)
+		}
+		return s.Reply(m.String())
+	}
+	switch kind {
+	case MediaVoice:
+		return s.sendQuoted("/sendVoice", "voiceurl", payload, messageid, p)
+	case MediaVideo:
+		return s.sendQuoted("/sendVideo", "videourl", payload, messageid, p)
+	default:
+		return s.sendQuoted("/sendImage", "imageurl", payload, messageid, p)
+	}
+}
+
+func (s *Sender) quoteID(messageid string) string {
+	if messageid == "" {
+		return s.GetMessageID()
+	}
+	return messageid
+}
+
+// 以引用开头的消息，消息ID为空时不引用
+func quoteMessage(messageid string) *Message {
+	m := NewMessage()
+	if messageid != "" {
+		m.Reply(messageid)
+	}
+	return m
+}
+
+// 发送消息，平台支持原生引用时附带被引用的消息ID
+func (s *Sender) sendQuoted(path, key, value, messageid string, p Platform) ([]string, error) {
+	params := map[string]interface{}{
+		"senderid": s.SenderID,
+		key:        value,
+	}
+	if p.Quote && messageid != "" {
+		params["replyto"] = messageid
+	}
+	body, _ := json.Marshal(params)
+	var msgIds []string
+	if resp, err := httplib.Post(sockUrl()+path).Header("Content-Type", "application/json").Body(body).SetTransport(transport).Bytes(); err == nil {
+		if data, err := jsonparser.GetUnsafeString(resp, "data"); err == nil {
+			json.Unmarshal([]byte(data), &msgIds)
+			return msgIds, nil
+		}
+	}
+	return nil, errors.New("回复失败")
+}
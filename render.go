@@ -0,0 +1,234 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/beego/beego/v2/client/httplib"
+	"github.com/buger/jsonparser"
+)
+
+var (
+	mdFence      = regexp.MustCompile("^\\s*```")
+	mdHeading    = regexp.MustCompile(`^\s{0,3}#{1,6}\s+`)
+	mdQuote      = regexp.MustCompile(`^\s{0,3}>\s?`)
+	mdImage      = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)[^)]*\)`)
+	mdLink       = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)[^)]*\)`)
+	mdBold       = regexp.MustCompile(`\*\*(.+?)\*\*|__(.+?)__`)
+	mdItalic     = regexp.MustCompile(`\*([^*\s][^*]*?)\*|\b_([^_\s][^_]*?)_\b`)
+	mdStrike     = regexp.MustCompile(`~~(.+?)~~`)
+	mdInlineCode = regexp.MustCompile("`([^`]+)`")
+)
+
+/**
+ * @description: 按目标平台渲染消息构造器生成的内容
+ * 支持CQ码的平台直接输出CQ码；tg输出HTML；其他平台输出纯文本，@渲染为"@用户ID"
+ * 图片、语音、视频仍以CQ码输出，由autMan适配器负责发送
+ * @param {string} imtype 平台类型
+ * @param {*Message} m 消息
+ * @return {string}
+ */
+func RenderMessage(imtype string, m *Message) string {
+	p := PlatformOf(imtype)
+	if p.CQCode {
+		return m.String()
+	}
+	var sb strings.Builder
+	for _, seg := range m.Segments {
+		switch seg.Type {
+		case "text":
+			if p.HTML {
+				sb.WriteString(html.EscapeString(seg.Data["text"]))
+			} else {
+				sb.WriteString(seg.Data["text"])
+			}
+		case "at":
+			id := seg.Data["qq"]
+			if id == "all" {
+				sb.WriteString("@所有人 ")
+			} else if p.HTML {
+				sb.WriteString(`<a href="tg://user?id=` + html.EscapeString(id) + `">@` + html.EscapeString(id) + `</a> `)
+			} else {
+				sb.WriteString("@" + id + " ")
+			}
+		case "image", "record", "video", "file":
+			sb.WriteString(seg.String())
+		}
+	}
+	return sb.String()
+}
+
+/**
+ * @description: 按目标平台渲染markdown
+ * 支持markdown的平台原样返回；tg转换为HTML；其他平台转换为纯文本
+ * @param {string} imtype 平台类型
+ * @param {string} md markdown字符串
+ * @return {string}
+ */
+func RenderMarkdown(imtype, md string) string {
+	p := PlatformOf(imtype)
+	switch {
+	case p.Markdown:
+		return md
+	case p.HTML:
+		return MarkdownToHTML(md)
+	default:
+		return MarkdownToText(md)
+	}
+}
+
+/**
+ * @description: markdown转纯文本，图片转换为CQ码，链接保留地址
+ * @param {string} md markdown字符串
+ * @return {string}
+ */
+func MarkdownToText(md string) string {
+	lines := strings.Split(md, "\n")
+	out := make([]string, 0, len(lines))
+	inFence := false
+	for _, line := range lines {
+		if mdFence.MatchString(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			out = append(out, line)
+			continue
+		}
+		line = mdHeading.ReplaceAllString(line, "")
+		line = mdQuote.ReplaceAllString(line, "")
+		line = mapInlineCode(line, func(code string) string { return code }, func(text string) string {
+			text = mdImage.ReplaceAllStringFunc(text, func(s string) string {
+				sub := mdImage.FindStringSubmatch(s)
+				return NewMessage().Image(sub[2]).String()
+			})
+			text = mdLink.ReplaceAllString(text, "$1 ($2)")
+			text = mdBold.ReplaceAllString(text, "$1$2")
+			text = mdItalic.ReplaceAllString(text, "$1$2")
+			return mdStrike.ReplaceAllString(text, "$1")
+		})
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
+
+/**
+ * @description: markdown转telegram支持的HTML子集
+ * @param {string} md markdown字符串
+ * @return {string}
+ */
+func MarkdownToHTML(md string) string {
+	lines := strings.Split(md, "\n")
+	out := make([]string, 0, len(lines))
+	inFence := false
+	for _, line := range lines {
+		if mdFence.MatchString(line) {
+			if inFence {
+				out[len(out)-1] += "</pre>"
+			} else {
+				out = append(out, "<pre>")
+			}
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			if last := out[len(out)-1]; last == "<pre>" {
+				out[len(out)-1] = last + html.EscapeString(line)
+			} else {
+				out[len(out)-1] = last + "\n" + html.EscapeString(line)
+			}
+			continue
+		}
+		heading := mdHeading.MatchString(line)
+		line = mdHeading.ReplaceAllString(line, "")
+		line = mdQuote.ReplaceAllString(line, "")
+		line = mapInlineCode(line, func(code string) string {
+			return "<code>" + html.EscapeString(code) + "</code>"
+		}, func(text string) string {
+			text = html.EscapeString(text)
+			text = mdImage.ReplaceAllString(text, `<a href="$2">$1</a>`)
+			text = mdLink.ReplaceAllString(text, `<a href="$2">$1</a>`)
+			text = mdBold.ReplaceAllString(text, "<b>$1$2</b>")
+			text = mdItalic.ReplaceAllString(text, "<i>$1$2</i>")
+			return mdStrike.ReplaceAllString(text, "<s>$1</s>")
+		})
+		if heading {
+			line = "<b>" + line + "</b>"
+		}
+		out = append(out, line)
+	}
+	if inFence {
+		out[len(out)-1] += "</pre>"
+	}
+	return strings.Join(out, "\n")
+}
+
+// 分别处理行内代码与其余文本，行内代码的内容不参与加粗、链接等转换
+func mapInlineCode(line string, code, text func(string) string) string {
+	var sb strings.Builder
+	last := 0
+	for _, m := range mdInlineCode.FindAllStringSubmatchIndex(line, -1) {
+		sb.WriteString(text(line[last:m[0]]))
+		sb.WriteString(code(line[m[2]:m[3]]))
+		last = m[1]
+	}
+	sb.WriteString(text(line[last:]))
+	return sb.String()
+}
+
+/**
+ * @description: 按当前平台渲染并回复消息构造器生成的内容
+ * @param {*Message} m 消息
+ * @return {[]string} 消息ID
+ */
+func (s *Sender) ReplyRendered(m *Message) ([]string, error) {
+	imtype := s.GetImtype()
+	if PlatformOf(imtype).HTML {
+		return s.replyFormat(RenderMessage(imtype, m), "html")
+	}
+	return s.Reply(RenderMessage(imtype, m))
+}
+
+/**
+ * @description: 按当前平台回复markdown，不支持markdown的平台自动降级
+ * @param {string} md markdown字符串
+ * @return {[]string} 消息ID
+ */
+func (s *Sender) ReplyMarkdownAuto(md string) ([]string, error) {
+	imtype := s.GetImtype()
+	p := PlatformOf(imtype)
+	switch {
+	case p.Markdown:
+		return s.ReplyMarkdown(md)
+	case p.HTML:
+		return s.replyFormat(MarkdownToHTML(md), "html")
+	default:
+		return s.Reply(MarkdownToText(md))
+	}
+}
+
+/**
+ * @description: 回复带格式声明的文本
+ * @param {string} text 文本内容
+ * @param {string} format 文本格式，如html
+ * @return {[]string} 消息ID
+ */
+func (s *Sender) replyFormat(text, format string) ([]string, error) {
+	params := map[string]interface{}{
+		"senderid": s.SenderID,
+		"text":     text,
+		"format":   format,
+	}
+	body, _ := json.Marshal(params)
+	var msgIds []string
+	if resp, err := httplib.Post(sockUrl()+"/sendText").Header("Content-Type", "application/json").Body(body).SetTransport(transport).Bytes(); err == nil {
+		if data, err := jsonparser.GetUnsafeString(resp, "data"); err == nil {
+			json.Unmarshal([]byte(data), &msgIds)
+			return msgIds, nil
+		}
+	}
+	return nil, errors.New("回复失败")
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkdownToText(t *testing.T) {
+	tests := []struct {
+		name string
+		md   string
+		want string
+	}{
+		{"标题", "# 标题\n### 小节", "标题\n小节"},
+		{"引用", "> 引用内容", "引用内容"},
+		{"强调", "**粗体** *斜体* __粗__ ~~删除~~", "粗体 斜体 粗 删除"},
+		{"下划线不是斜体", "snake_case_name", "snake_case_name"},
+		{"链接保留地址", "[搜索](https://a.com/?q=1&b=2)", "搜索 (https://a.com/?q=1&b=2)"},
+		{"图片转CQ码", "![图](http://x/a.jpg)", "[CQ:image,file=http://x/a.jpg]"},
+		{"行内代码不转换", "运行 `**x** [a](b)` 后", "运行 **x** [a](b) 后"},
+		{"代码块原样保留", "```go\n**x** # y\n```\n**z**", "**x** # y\nz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MarkdownToText(tt.md); got != tt.want {
+				t.Errorf("MarkdownToText(%q) = %q, want %q", tt.md, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkdownToHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		md   string
+		want string
+	}{
+		{"标题加粗", "## 标题", "<b>标题</b>"},
+		{"强调", "**粗** *斜* ~~删~~", "<b>粗</b> <i>斜</i> <s>删</s>"},
+		{"转义", "a < b & c", "a &lt; b &amp; c"},
+		{"链接中的&", "[搜索](https://a.com/?q=1&b=2)", `<a href="https://a.com/?q=1&amp;b=2">搜索</a>`},
+		{"行内代码", "用 `<b>**x**</b>` 加粗", "用 <code>&lt;b&gt;**x**&lt;/b&gt;</code> 加粗"},
+		{"代码块", "```\nif a < b {\n  **x**\n}\n```", "<pre>if a &lt; b {\n  **x**\n}</pre>"},
+		{"未闭合的代码块", "```\ncode", "<pre>code</pre>"},
+		{"代码块前后的文本", "前\n```\nx\n```\n**后**", "前\n<pre>x</pre>\n<b>后</b>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MarkdownToHTML(tt.md); got != tt.want {
+				t.Errorf("MarkdownToHTML(%q) = %q, want %q", tt.md, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapInlineCode(t *testing.T) {
+	code := func(s string) string { return "<" + s + ">" }
+	text := strings.ToUpper
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"abc", "ABC"},
+		{"a `b` c", "A <b> C"},
+		{"`a``b`", "<a><b>"},
+		{"a `unclosed", "A `UNCLOSED"},
+		{"``", "``"},
+	}
+	for _, tt := range tests {
+		if got := mapInlineCode(tt.line, code, text); got != tt.want {
+			t.Errorf("mapInlineCode(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/beego/beego/v2/client/httplib"
+)
+
+/**
+ * @description: 同意好友申请、入群申请或入群邀请
+ * @param {*RequestEvent} req 请求事件
+ */
+func ApproveRequest(req *RequestEvent) error {
+	return handleRequest(req, true, "")
+}
+
+/**
+ * @description: 拒绝好友申请、入群申请或入群邀请
+ * @param {*RequestEvent} req 请求事件
+ * @param {string} reason 拒绝理由，部分平台会展示给申请人
+ */
+func RejectRequest(req *RequestEvent, reason string) error {
+	return handleRequest(req, false, reason)
+}
+
+func handleRequest(req *RequestEvent, approve bool, reason string) error {
+	params := map[string]interface{}{
+		"imType":  req.Imtype,
+		"kind":    req.Kind,
+		"flag":    req.Flag,
+		"chatid":  req.ChatID,
+		"userid":  req.UserID,
+		"approve": approve,
+		"reason":  reason,
+	}
+	body, _ := json.Marshal(params)
+	_, err := httplib.Post(sockUrl()+"/handleRequest").Header("Content-Type", "application/json").Body(body).SetTransport(transport).Bytes()
+	return err
+}
+
+/**
+ * @description: 自动审核规则，验证信息包含任一答案或申请人在白名单中时通过
+ */
+type AutoApprove struct {
+	Kinds           []string // 处理的请求类型，为空时处理全部类型
+	Answers         []string // 验证信息包含任一答案时通过，不区分大小写
+	WhitelistBucket string   // 申请人用户ID在该数据桶中有值时通过
+	RejectReason    string   // 不满足条件时的拒绝理由，为空时不拒绝，留待人工审核
+}
+
+/**
+ * @description: 按规则处理请求
+ * @param {*RequestEvent} req 请求事件
+ * @return {bool} 是否已处理（通过或拒绝）
+ */
+func (a *AutoApprove) Handle(req *RequestEvent) (bool, error) {
+	if len(a.Kinds) > 0 && !containsString(a.Kinds, req.Kind) {
+		return false, nil
+	}
+	if a.Match(req) {
+		return true, ApproveRequest(req)
+	}
+	if a.RejectReason != "" {
+		return true, RejectRequest(req, a.RejectReason)
+	}
+	return false, nil
+}
+
+/**
+ * @description: 请求是否满足通过条件
+ * @param {*RequestEvent} req 请求事件
+ * @return {bool}
+ */
+func (a *AutoApprove) Match(req *RequestEvent) bool {
+	comment := strings.ToLower(req.Comment)
+	for _, answer := range a.Answers {
+		if answer != "" && strings.Contains(comment, strings.ToLower(answer)) {
+			return true
+		}
+	}
+	return a.WhitelistBucket != "" && BucketGet(a.WhitelistBucket, req.UserID) != ""
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
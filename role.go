@@ -0,0 +1,427 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 保存角色定义与授权的数据桶
+const roleBucket = "role"
+
+// 内置权限
+const (
+	PermAll         = "*"            // 全部权限
+	PermRoleManage  = "role.manage"  // 授予、撤销角色
+	PermGroupManage = "group.manage" // 群管理，与群管理员身份等效
+)
+
+var (
+	ErrPermissionDenied = errors.New("权限不足")
+	ErrGlobalScope      = errors.New("只有全局拥有角色管理权限的用户才能管理全局角色")
+)
+
+// 同一进程内串行化角色数据的读写
+var roleMu sync.Mutex
+
+/**
+ * @description: 角色，拥有自身的权限以及Inherits中全部角色的权限
+ */
+type Role struct {
+	Name        string   `json:"name"`
+	Inherits    []string `json:"inherits,omitempty"`
+	Permissions []string `json:"permissions"` // 权限名称，支持"*"和"group.*"形式的通配
+}
+
+/**
+ * @description: 定义或更新角色
+ * @param {Role} role 角色
+ */
+func DefineRole(role Role) error {
+	if role.Name == "" {
+		return errors.New("角色名称不能为空")
+	}
+	data, _ := json.Marshal(role)
+	return BucketSet(roleBucket, "def:"+role.Name, string(data))
+}
+
+/**
+ * @description: 获取角色定义
+ * @param {string} name 角色名称
+ * @return {*Role} 角色未定义时为nil
+ */
+func GetRole(name string) *Role {
+	data := BucketGet(roleBucket, "def:"+name)
+	if data == "" {
+		return nil
+	}
+	role := &Role{}
+	if json.Unmarshal([]byte(data), role) != nil {
+		return nil
+	}
+	return role
+}
+
+/**
+ * @description: 删除角色定义，已授予的角色不会自动撤销，但不再拥有任何权限
+ * @param {string} name 角色名称
+ */
+func DeleteRole(name string) error {
+	return BucketDelete(roleBucket, "def:"+name)
+}
+
+// chatid为空或为"0"（私聊）时为全局授权，否则仅在该群内有效
+func roleKey(imtype, chatid, userid string) string {
+	if isPrivateChat(chatid) {
+		return "user:" + imtype + ":" + userid
+	}
+	return "chat:" + imtype + ":" + chatid + ":" + userid
+}
+
+func isPrivateChat(chatid string) bool {
+	return chatid == "" || chatid == "0"
+}
+
+func loadRoles(key string) []string {
+	var roles []string
+	if data := BucketGet(roleBucket, key); data != "" {
+		json.Unmarshal([]byte(data), &roles)
+	}
+	return roles
+}
+
+/**
+ * @description: 授予用户角色
+ * @param {string} imtype 平台
+ * @param {string} chatid 群号，为空或为"0"时在所有会话中有效
+ * @param {string} userid 用户ID
+ * @param {string} role 角色名称
+ */
+func GrantRole(imtype, chatid, userid, role string) error {
+	if GetRole(role) == nil {
+		return fmt.Errorf("角色%s未定义", role)
+	}
+	roleMu.Lock()
+	defer roleMu.Unlock()
+	key := roleKey(imtype, chatid, userid)
+	roles := loadRoles(key)
+	if containsString(roles, role) {
+		return nil
+	}
+	data, _ := json.Marshal(append(roles, role))
+	return BucketSet(roleBucket, key, string(data))
+}
+
+/**
+ * @description: 撤销用户角色，只撤销对应范围内的授权
+ * @param {string} imtype 平台
+ * @param {string} chatid 群号，为空或为"0"时撤销全局授权
+ * @param {string} userid 用户ID
+ * @param {string} role 角色名称
+ */
+func RevokeRole(imtype, chatid, userid, role string) error {
+	roleMu.Lock()
+	defer roleMu.Unlock()
+	key := roleKey(imtype, chatid, userid)
+	roles := loadRoles(key)
+	kept := roles[:0]
+	for _, r := range roles {
+		if r != role {
+			kept = append(kept, r)
+		}
+	}
+	if len(kept) == 0 {
+		return BucketDelete(roleBucket, key)
+	}
+	data, _ := json.Marshal(kept)
+	return BucketSet(roleBucket, key, string(data))
+}
+
+/**
+ * @description: 获取用户在会话中的角色，包括全局授权与群内授权，不含继承的角色
+ * @param {string} imtype 平台
+ * @param {string} chatid 群号，为空时只返回全局授权
+ * @param {string} userid 用户ID
+ * @return {[]string}
+ */
+func UserRoles(imtype, chatid, userid string) []string {
+	roles := loadRoles(roleKey(imtype, "", userid))
+	if !isPrivateChat(chatid) {
+		for _, r := range loadRoles(roleKey(imtype, chatid, userid)) {
+			if !containsString(roles, r) {
+				roles = append(roles, r)
+			}
+		}
+	}
+	return roles
+}
+
+/**
+ * @description: 获取用户在会话中的全部权限，包括继承自其他角色的权限
+ * @param {string} imtype 平台
+ * @param {string} chatid 群号
+ * @param {string} userid 用户ID
+ * @return {[]string}
+ */
+func UserPermissions(imtype, chatid, userid string) []string {
+	return RolePermissions(UserRoles(imtype, chatid, userid)...)
+}
+
+/**
+ * @description: 获取角色的全部权限，包括继承自其他角色的权限
+ * @param {...string} names 角色名称
+ * @return {[]string}
+ */
+func RolePermissions(names ...string) []string {
+	perms := map[string]bool{}
+	seen := map[string]bool{}
+	var walk func(name string)
+	walk = func(name string) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		role := GetRole(name)
+		if role == nil {
+			return
+		}
+		for _, p := range role.Permissions {
+			perms[p] = true
+		}
+		for _, parent := range role.Inherits {
+			walk(parent)
+		}
+	}
+	for _, name := range names {
+		walk(name)
+	}
+	list := make([]string, 0, len(perms))
+	for p := range perms {
+		list = append(list, p)
+	}
+	sort.Strings(list)
+	return list
+}
+
+/**
+ * @description: 用户在会话中是否拥有权限
+ * @param {string} imtype 平台
+ * @param {string} chatid 群号
+ * @param {string} userid 用户ID
+ * @param {string} perm 权限名称
+ * @return {bool}
+ */
+func HasPermission(imtype, chatid, userid, perm string) bool {
+	for _, p := range UserPermissions(imtype, chatid, userid) {
+		if MatchPermission(p, perm) {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * @description: 权限是否覆盖另一权限，"*"覆盖全部，"group.*"覆盖"group.kick"等
+ * @param {string} granted 已拥有的权限
+ * @param {string} perm 需要的权限
+ * @return {bool}
+ */
+func MatchPermission(granted, perm string) bool {
+	if granted == PermAll || granted == perm {
+		return true
+	}
+	return strings.HasSuffix(granted, ".*") && strings.HasPrefix(perm, strings.TrimSuffix(granted, "*"))
+}
+
+/**
+ * @description: 当前用户在当前会话中的角色
+ * @return {[]string}
+ */
+func (s *Sender) Roles() []string {
+	return UserRoles(s.GetImtype(), s.GetChatID(), s.GetUserID())
+}
+
+/**
+ * @description: 当前用户在当前会话中是否拥有角色，包括继承关系
+ * @param {string} role 角色名称
+ * @return {bool}
+ */
+func (s *Sender) HasRole(role string) bool {
+	seen := map[string]bool{}
+	var walk func(name string) bool
+	walk = func(name string) bool {
+		if name == role {
+			return true
+		}
+		if seen[name] {
+			return false
+		}
+		seen[name] = true
+		if r := GetRole(name); r != nil {
+			for _, parent := range r.Inherits {
+				if walk(parent) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	for _, r := range s.Roles() {
+		if walk(r) {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * @description: 当前用户在当前会话中是否拥有权限，autMan管理员拥有全部权限
+ * @param {string} perm 权限名称
+ * @return {bool}
+ */
+func (s *Sender) Can(perm string) bool {
+	return s.IsAdmin() || HasPermission(s.GetImtype(), s.GetChatID(), s.GetUserID(), perm)
+}
+
+/**
+ * @description: 要求当前用户拥有权限，否则回复"权限不足"并返回ErrPermissionDenied
+ * @param {string} perm 权限名称
+ * @return {Middleware}
+ */
+func RequirePermission(perm string) Middleware {
+	return guard(func(s *Sender) bool { return s.Can(perm) })
+}
+
+/**
+ * @description: 要求当前用户拥有角色，autMan管理员不受限制
+ * @param {string} role 角色名称
+ * @return {Middleware}
+ */
+func RequireRole(role string) Middleware {
+	return guard(func(s *Sender) bool { return s.IsAdmin() || s.HasRole(role) })
+}
+
+/**
+ * @description: 要求当前用户为autMan管理员
+ * @return {Middleware}
+ */
+func RequireAdmin() Middleware {
+	return guard(func(s *Sender) bool { return s.IsAdmin() })
+}
+
+func guard(allow func(s *Sender) bool) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(s *Sender, args Args) error {
+			if !allow(s) {
+				s.Reply(ErrPermissionDenied.Error())
+				return ErrPermissionDenied
+			}
+			return next(s, args)
+		}
+	}
+}
+
+/**
+ * @description: 检查当前用户能否在chatid范围内授予或撤销角色：
+ * 管理全局角色需要全局的PermRoleManage权限，且角色的全部权限（含继承）当前用户都必须拥有，autMan管理员不受限制
+ * @param {string} chatid 群号，为空或为"0"时为全局范围
+ * @param {string} role 角色名称
+ */
+func (s *Sender) CanAssignRole(chatid, role string) error {
+	if s.IsAdmin() {
+		return nil
+	}
+	imtype, userid := s.GetImtype(), s.GetUserID()
+	if isPrivateChat(chatid) {
+		chatid = ""
+		if !HasPermission(imtype, "", userid, PermRoleManage) {
+			return ErrGlobalScope
+		}
+	} else if !HasPermission(imtype, chatid, userid, PermRoleManage) {
+		return ErrPermissionDenied
+	}
+	for _, perm := range RolePermissions(role) {
+		if !HasPermission(imtype, chatid, userid, perm) {
+			return fmt.Errorf("%w: 角色%s包含你没有的权限%s", ErrPermissionDenied, role, perm)
+		}
+	}
+	return nil
+}
+
+/**
+ * @description: 注册角色管理命令grant、revoke、roles，需要PermRoleManage权限
+ * scope为chat时仅在当前群内有效，为global时在所有会话中有效，私聊中始终为global
+ * grant与revoke还会通过CanAssignRole检查范围，且只能管理自己拥有全部权限的角色
+ * @return {*Router}
+ */
+func (r *Router) RegisterRoleCommands() *Router {
+	scope := Arg{Name: "scope", Type: ArgChoice, Choices: []string{"chat", "global"}, Optional: true, Default: "chat"}
+	chatOf := func(s *Sender, args Args) string {
+		if chatid := s.GetChatID(); args.String("scope") != "global" && !isPrivateChat(chatid) {
+			return chatid
+		}
+		return ""
+	}
+	manage := []Middleware{RequirePermission(PermRoleManage)}
+	r.Register(&Command{
+		Name:   "grant",
+		Help:   "授予角色",
+		Args:   []Arg{{Name: "user", Type: ArgUser}, {Name: "role"}, scope},
+		Guards: manage,
+		Handler: func(s *Sender, args Args) error {
+			chatid := chatOf(s, args)
+			if err := s.CanAssignRole(chatid, args.String("role")); err != nil {
+				s.Reply(err.Error())
+				return err
+			}
+			if err := GrantRole(s.GetImtype(), chatid, args.String("user"), args.String("role")); err != nil {
+				s.Reply(err.Error())
+				return err
+			}
+			_, err := s.Reply(fmt.Sprintf("已授予%s角色%s", args.String("user"), args.String("role")))
+			return err
+		},
+	})
+	r.Register(&Command{
+		Name:   "revoke",
+		Help:   "撤销角色",
+		Args:   []Arg{{Name: "user", Type: ArgUser}, {Name: "role"}, scope},
+		Guards: manage,
+		Handler: func(s *Sender, args Args) error {
+			chatid := chatOf(s, args)
+			if err := s.CanAssignRole(chatid, args.String("role")); err != nil {
+				s.Reply(err.Error())
+				return err
+			}
+			if err := RevokeRole(s.GetImtype(), chatid, args.String("user"), args.String("role")); err != nil {
+				s.Reply(err.Error())
+				return err
+			}
+			_, err := s.Reply(fmt.Sprintf("已撤销%s的角色%s", args.String("user"), args.String("role")))
+			return err
+		},
+	})
+	r.Register(&Command{
+		Name:   "roles",
+		Help:   "查看用户角色",
+		Args:   []Arg{{Name: "user", Type: ArgUser, Optional: true}},
+		Guards: manage,
+		Handler: func(s *Sender, args Args) error {
+			user := args.String("user")
+			if user == "" {
+				user = s.GetUserID()
+			}
+			roles := UserRoles(s.GetImtype(), s.GetChatID(), user)
+			if len(roles) == 0 {
+				_, err := s.Reply(user + "没有任何角色")
+				return err
+			}
+			_, err := s.Reply(user + "的角色: " + strings.Join(roles, "、"))
+			return err
+		},
+	})
+	return r
+}
@@ -0,0 +1,242 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 保存定时任务的数据桶
+const scheduleBucket = "schedule"
+
+/**
+ * @description: 定时任务检查间隔
+ */
+var ScheduleTick = 5 * time.Second
+
+/**
+ * @description: 一次性任务最多推送的次数，全部失败后任务标记为Dead，不再执行
+ */
+var ScheduleMaxAttempts = 8
+
+// 一次性任务重试间隔的上限
+const scheduleMaxDelay = 30 * time.Minute
+
+/**
+ * @description: 定时推送任务
+ */
+type ScheduledJob struct {
+	ID        string `json:"id"`
+	ImType    string `json:"imType"`
+	GroupCode string `json:"groupCode"`
+	UserID    string `json:"userID"`
+	Title     string `json:"title"`
+	Content   string `json:"content"`
+	Cron      string `json:"cron"`      // 周期任务的cron表达式，一次性任务为空
+	Next      int64  `json:"next"`      // 下次执行时间，unix时间戳，单位：秒
+	Attempts  int    `json:"attempts"`  // 连续推送失败的次数
+	LastError string `json:"lastError"` // 最近一次推送失败的原因
+	Dead      bool   `json:"dead"`      // 一次性任务多次推送失败后不再执行，可通过CancelSchedule删除
+}
+
+/**
+ * @description: 在指定时间推送消息，任务保存在数据桶中，由RunScheduler执行
+ * @param {time.Time} at 推送时间
+ * @param {string} imType 包括：qq/qb/wx/wb/tg/tb/wxmp/wxsv
+ * @param {string} groupCode 群号
+ * @param {string} userID 用户ID
+ * @param {string} title 标题
+ * @param {string} content 内容
+ * @return {string} 任务ID
+ */
+func SchedulePush(at time.Time, imType, groupCode, userID, title, content string) (string, error) {
+	job := &ScheduledJob{
+		ID:        newJobID(),
+		ImType:    imType,
+		GroupCode: groupCode,
+		UserID:    userID,
+		Title:     title,
+		Content:   content,
+		Next:      at.Unix(),
+	}
+	return job.ID, saveJob(job)
+}
+
+/**
+ * @description: 按cron表达式周期推送消息，任务保存在数据桶中，由RunScheduler执行
+ * @param {string} spec cron表达式，格式为"分 时 日 月 周"
+ * @param {string} imType 包括：qq/qb/wx/wb/tg/tb/wxmp/wxsv
+ * @param {string} groupCode 群号
+ * @param {string} userID 用户ID
+ * @param {string} title 标题
+ * @param {string} content 内容
+ * @return {string} 任务ID
+ */
+func ScheduleCron(spec, imType, groupCode, userID, title, content string) (string, error) {
+	cron, err := ParseCron(spec)
+	if err != nil {
+		return "", err
+	}
+	next := cron.Next(time.Now())
+	if next.IsZero() {
+		return "", errors.New("cron表达式没有可执行的时间")
+	}
+	job := &ScheduledJob{
+		ID:        newJobID(),
+		ImType:    imType,
+		GroupCode: groupCode,
+		UserID:    userID,
+		Title:     title,
+		Content:   content,
+		Cron:      spec,
+		Next:      next.Unix(),
+	}
+	return job.ID, saveJob(job)
+}
+
+/**
+ * @description: 获取全部定时任务，按下次执行时间排序
+ * @return {[]ScheduledJob}
+ */
+func ScheduledJobs() []ScheduledJob {
+	jobs := []ScheduledJob{}
+	for _, id := range BucketAllKeys(scheduleBucket) {
+		if job := loadJob(id); job != nil {
+			jobs = append(jobs, *job)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Next < jobs[j].Next })
+	return jobs
+}
+
+/**
+ * @description: 取消定时任务
+ * @param {string} id 任务ID
+ */
+func CancelSchedule(id string) error {
+	scheduleMu.Lock()
+	defer scheduleMu.Unlock()
+	return BucketDelete(scheduleBucket, id)
+}
+
+// 串行化取消任务与更新下次执行时间，避免已取消的任务被重新写入
+var scheduleMu sync.Mutex
+
+/**
+ * @description: 运行定时任务，阻塞直到exitChannel关闭
+ * 插件重启后错过的一次性任务会立即补发，周期任务从当前时间起计算下次执行时间。
+ * 一次性任务推送失败时按指数退避重试，超过ScheduleMaxAttempts次后标记为Dead；
+ * 周期任务推送失败时只记录失败原因，等待下次执行时间
+ */
+func RunScheduler(exitChannel chan struct{}) {
+	ticker := time.NewTicker(ScheduleTick)
+	defer ticker.Stop()
+	runDueJobs(true)
+	for {
+		select {
+		case <-exitChannel:
+			return
+		case <-ticker.C:
+			runDueJobs(false)
+		}
+	}
+}
+
+func runDueJobs(startup bool) {
+	now := time.Now()
+	for _, job := range ScheduledJobs() {
+		if job.Next > now.Unix() {
+			break
+		}
+		if job.Dead {
+			continue
+		}
+		job := job
+		if job.Cron == "" {
+			err := Push(job.ImType, job.GroupCode, job.UserID, job.Title, job.Content)
+			if err == nil {
+				CancelSchedule(job.ID)
+				continue
+			}
+			// 推送失败（如autMan不可用）时保留任务，退避后重试
+			job.Attempts++
+			job.LastError = err.Error()
+			if job.Attempts >= ScheduleMaxAttempts {
+				job.Dead = true
+			} else {
+				job.Next = now.Add(scheduleDelay(job.Attempts)).Unix()
+			}
+			updateJob(&job)
+			continue
+		}
+		cron, err := ParseCron(job.Cron)
+		if err != nil {
+			CancelSchedule(job.ID)
+			continue
+		}
+		if !startup || now.Unix()-job.Next < int64(ScheduleTick/time.Second)*2 {
+			if err := Push(job.ImType, job.GroupCode, job.UserID, job.Title, job.Content); err != nil {
+				job.Attempts++
+				job.LastError = err.Error()
+			} else {
+				job.Attempts = 0
+				job.LastError = ""
+			}
+		}
+		next := cron.Next(now)
+		if next.IsZero() {
+			CancelSchedule(job.ID)
+			continue
+		}
+		job.Next = next.Unix()
+		updateJob(&job)
+	}
+}
+
+// 更新任务，任务已被取消时不再写入
+func updateJob(job *ScheduledJob) {
+	scheduleMu.Lock()
+	defer scheduleMu.Unlock()
+	if loadJob(job.ID) != nil {
+		saveJob(job)
+	}
+}
+
+// 一次性任务第attempts次失败后的重试间隔，从ScheduleTick开始翻倍
+func scheduleDelay(attempts int) time.Duration {
+	d := ScheduleTick
+	for i := 1; i < attempts && d < scheduleMaxDelay; i++ {
+		d *= 2
+	}
+	if d > scheduleMaxDelay {
+		d = scheduleMaxDelay
+	}
+	return d
+}
+
+func newJobID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func loadJob(id string) *ScheduledJob {
+	data := BucketGet(scheduleBucket, id)
+	if data == "" {
+		return nil
+	}
+	job := &ScheduledJob{}
+	if json.Unmarshal([]byte(data), job) != nil {
+		return nil
+	}
+	return job
+}
+
+func saveJob(job *ScheduledJob) error {
+	data, _ := json.Marshal(job)
+	return BucketSet(scheduleBucket, job.ID, string(data))
+}
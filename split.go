@@ -0,0 +1,264 @@
+package middleware
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+/**
+ * @description: 长消息拆分选项
+ */
+type SplitOptions struct {
+	Limit    int  // 每段最大字符数，为0时按当前平台限制
+	Paginate bool // 是否在每段末尾添加"(1/3)"样式的分页标记
+}
+
+// 分页标记预留的字符数
+const pageMarkReserve = 10
+
+// 拆分断点优先级
+const (
+	breakNone = iota
+	breakSpace
+	breakLine
+	breakParagraph
+)
+
+// 拆分的最小单元：一个字素或一个完整的CQ码
+type splitUnit struct {
+	text    string
+	size    int    // 字符数
+	fence   bool   // 是否位于代码块内
+	opening bool   // 是否为代码块起始行及其换行
+	closing bool   // 是否为代码块结束行及其前的换行
+	opener  string // 所在代码块的起始行，用于续接代码块
+}
+
+/**
+ * @description: 将长文本拆分为多段，优先在段落、换行、空格处断开，不会拆开CQ码、字素和markdown代码块
+ * 代码块本身超长时，在块内换行处断开，并自动闭合、续接代码块；只含空白的文本不拆分，原样返回
+ * @param {string} text 文本内容
+ * @param {SplitOptions} opts 拆分选项，Limit为0时使用DefaultMaxLength
+ * @return {[]string}
+ */
+func SplitText(text string, opts SplitOptions) []string {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultMaxLength
+	}
+	if utf8.RuneCountInString(text) <= limit || strings.TrimSpace(text) == "" {
+		return []string{text}
+	}
+	if opts.Paginate && limit > pageMarkReserve*2 {
+		limit -= pageMarkReserve
+	}
+	units := splitUnits(text)
+	var parts []string
+	for start := 0; start < len(units); {
+		for start < len(units) && !units[start].fence && strings.TrimSpace(units[start].text) == "" {
+			start++
+		}
+		if start >= len(units) {
+			break
+		}
+		end := start + splitExtent(units, start, limit)
+		if end < len(units) {
+			end = splitBreak(units, start, end)
+		}
+		parts = append(parts, joinUnits(units, start, end))
+		start = end
+	}
+	if opts.Paginate && len(parts) > 1 {
+		for i := range parts {
+			parts[i] += fmt.Sprintf("\n(%d/%d)", i+1, len(parts))
+		}
+	}
+	return parts
+}
+
+// 计算从start开始在限制内最多能容纳的单元数，至少为1
+func splitExtent(units []splitUnit, start, limit int) int {
+	size := 0
+	if units[start].fence && !units[start].opening {
+		size += utf8.RuneCountInString(units[start].opener) + 1
+	}
+	n := 0
+	for i := start; i < len(units); i++ {
+		size += units[i].size
+		reserve := 0
+		if units[i].fence && !units[i].closing {
+			reserve = 4
+		}
+		if size+reserve > limit && n > 0 {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+// 在(start, end]内选择断点，返回新的end；优先代码块外、优先级高且靠后的断点
+func splitBreak(units []splitUnit, start, end int) int {
+	half := start + (end-start)/2
+	for _, inFence := range []bool{false, true} {
+		for p := breakParagraph; p >= breakNone; p-- {
+			min := half
+			if p == breakNone || inFence {
+				min = start
+			}
+			for i := end; i > min; i-- {
+				if units[i-1].opening || units[i].closing {
+					continue
+				}
+				if (units[i-1].fence && units[i].fence) == inFence && breakAt(units, i) >= p {
+					return i
+				}
+			}
+		}
+	}
+	return end
+}
+
+// 位置i之前的断点优先级
+func breakAt(units []splitUnit, i int) int {
+	switch units[i-1].text {
+	case "\n":
+		if i >= 2 && units[i-2].text == "\n" {
+			return breakParagraph
+		}
+		return breakLine
+	case " ", "\t":
+		return breakSpace
+	}
+	return breakNone
+}
+
+// 拼接单元，跨越代码块边界时闭合、续接代码块
+func joinUnits(units []splitUnit, start, end int) string {
+	var sb strings.Builder
+	if units[start].fence && !units[start].opening {
+		sb.WriteString(units[start].opener)
+		sb.WriteString("\n")
+	}
+	for i := start; i < end; i++ {
+		sb.WriteString(units[i].text)
+	}
+	s := strings.TrimRight(sb.String(), " \t\n")
+	if end < len(units) && units[end-1].fence && units[end].fence {
+		s += "\n```"
+	}
+	return s
+}
+
+// 将文本拆分为字素与CQ码单元，并标记代码块范围
+func splitUnits(text string) []splitUnit {
+	var units []splitUnit
+	fence, opener, opening := false, "", false
+	for li, line := range strings.Split(text, "\n") {
+		if li > 0 {
+			units = append(units, splitUnit{text: "\n", size: 1, fence: fence, opening: opening, opener: opener})
+		}
+		opening, closing := false, false
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "```") {
+			if fence {
+				closing = true
+				units[len(units)-1].closing = true
+			} else {
+				fence, opener, opening = true, trimmed, true
+			}
+		}
+		for line != "" {
+			var u string
+			if strings.HasPrefix(line, "[CQ:") {
+				if end := strings.Index(line, "]"); end > 0 {
+					u = line[:end+1]
+				}
+			}
+			if u == "" {
+				u = nextGrapheme(line)
+			}
+			units = append(units, splitUnit{text: u, size: utf8.RuneCountInString(u), fence: fence, opening: opening, closing: closing, opener: opener})
+			line = line[len(u):]
+		}
+		if closing {
+			fence, opener = false, ""
+		}
+	}
+	return units
+}
+
+// 取出字符串开头的一个字素，合并组合字符、变体选择符、肤色修饰符、零宽连接序列和国旗
+func nextGrapheme(s string) string {
+	r, n := utf8.DecodeRuneInString(s)
+	if r == '\r' && strings.HasPrefix(s[n:], "\n") {
+		return s[:n+1]
+	}
+	regional := isRegionalIndicator(r)
+	for n < len(s) {
+		next, size := utf8.DecodeRuneInString(s[n:])
+		switch {
+		case unicode.In(next, unicode.Mn, unicode.Me, unicode.Mc),
+			next >= 0xFE00 && next <= 0xFE0F,
+			next >= 0x1F3FB && next <= 0x1F3FF,
+			next >= 0xE0020 && next <= 0xE007F:
+			n += size
+		case next == 0x200D:
+			n += size
+			if n < len(s) {
+				_, size = utf8.DecodeRuneInString(s[n:])
+				n += size
+			}
+		case regional && isRegionalIndicator(next):
+			n += size
+			regional = false
+		default:
+			return s[:n]
+		}
+	}
+	return s[:n]
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+/**
+ * @description: 回复长文本，超出平台限制时自动拆分并按顺序发送
+ * @param {string} text 文本内容
+ * @param {SplitOptions} opts 可选，拆分选项
+ * @return {[]string} 所有分段的消息ID
+ */
+func (s *Sender) ReplyLong(text string, opts ...SplitOptions) ([]string, error) {
+	return s.replyParts(text, s.Reply, opts...)
+}
+
+/**
+ * @description: 回复长markdown，超出平台限制时自动拆分并按顺序发送
+ * @param {string} text markdown字符串
+ * @param {SplitOptions} opts 可选，拆分选项
+ * @return {[]string} 所有分段的消息ID
+ */
+func (s *Sender) ReplyMarkdownLong(text string, opts ...SplitOptions) ([]string, error) {
+	return s.replyParts(text, s.ReplyMarkdown, opts...)
+}
+
+func (s *Sender) replyParts(text string, send func(string) ([]string, error), opts ...SplitOptions) ([]string, error) {
+	opt := SplitOptions{}
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if opt.Limit <= 0 {
+		opt.Limit = MaxLengthOf(s.GetImtype())
+	}
+	var msgIds []string
+	for _, part := range SplitText(text, opt) {
+		ids, err := send(part)
+		if err != nil {
+			return msgIds, err
+		}
+		msgIds = append(msgIds, ids...)
+	}
+	return msgIds, nil
+}
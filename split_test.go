@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitText(t *testing.T) {
+	family := "👨‍👩‍👧"
+	tests := []struct {
+		name string
+		text string
+		opts SplitOptions
+		want []string
+	}{
+		{"短文本不拆分", "short", SplitOptions{Limit: 10}, []string{"short"}},
+		{"空格处断开", "aaaa bbbb cccc", SplitOptions{Limit: 10}, []string{"aaaa bbbb", "cccc"}},
+		{"换行优先于空格", "line one\nline two\nline three", SplitOptions{Limit: 12}, []string{"line one", "line two", "line three"}},
+		{"段落优先于换行", "para one.\n\npara two is here", SplitOptions{Limit: 20}, []string{"para one.", "para two is here"}},
+		{"无断点时按字符断开", "abcdefghijklmnop", SplitOptions{Limit: 5}, []string{"abcde", "fghij", "klmno", "p"}},
+		{"不拆开CQ码", "ab[CQ:image,file=x.jpg]cd", SplitOptions{Limit: 6}, []string{"ab", "[CQ:image,file=x.jpg]", "cd"}},
+		{"不拆开字素", family + family + family, SplitOptions{Limit: 6}, []string{family, family, family}},
+		{
+			"代码块内断开时闭合并续接",
+			"intro\n```go\nline1\nline2\nline3\n```\nend",
+			SplitOptions{Limit: 20},
+			[]string{"intro", "```go\nline1\n```", "```go\nline2\n```", "```go\nline3\n```\nend"},
+		},
+		{
+			"分页标记",
+			"aaaa bbbb cccc dddd eeee ffff gggg",
+			SplitOptions{Limit: 25, Paginate: true},
+			[]string{"aaaa bbbb cccc\n(1/3)", "dddd eeee ffff\n(2/3)", "gggg\n(3/3)"},
+		},
+		{"只有一段时不分页", "short", SplitOptions{Limit: 25, Paginate: true}, []string{"short"}},
+		{"只含空白时原样返回", " \n\n  \t ", SplitOptions{Limit: 3}, []string{" \n\n  \t "}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitText(tt.text, tt.opts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitText(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitTextInvariants(t *testing.T) {
+	texts := []string{
+		strings.Repeat("这是一段比较长的中文内容，", 40),
+		strings.Repeat("word ", 300),
+		strings.Repeat("para\n\n", 100) + strings.Repeat("x", 500),
+		"before\n```\n" + strings.Repeat("code line\n", 80) + "```\nafter " + strings.Repeat("tail ", 50),
+		strings.Repeat("text [CQ:at,qq=123456] ", 60),
+	}
+	for _, limit := range []int{30, 64, 200} {
+		for _, text := range texts {
+			parts := SplitText(text, SplitOptions{Limit: limit})
+			for _, part := range parts {
+				if n := utf8.RuneCountInString(part); n > limit {
+					t.Errorf("limit %d: part has %d characters: %q", limit, n, part)
+				}
+				if strings.Count(part, "```")%2 != 0 {
+					t.Errorf("limit %d: unbalanced code fence: %q", limit, part)
+				}
+				if strings.Count(part, "[CQ:") != strings.Count(part, "]") {
+					t.Errorf("limit %d: broken CQ code: %q", limit, part)
+				}
+			}
+			if got, want := splitContent(strings.Join(parts, "")), splitContent(text); got != want {
+				t.Errorf("limit %d: content changed after splitting", limit)
+			}
+		}
+	}
+}
+
+// 去掉空白与代码块标记后的内容，拆分不应增减其余字符
+func splitContent(s string) string {
+	return strings.Join(strings.Fields(strings.ReplaceAll(s, "```", "")), "")
+}